@@ -0,0 +1,91 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError returned for non 2xx responses when the client is configured to do so,
+// it keeps everything needed to inspect the failed response
+type HTTPError struct {
+	Method     string
+	URL        string
+	Status     string
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+	// Decoded the error body decoded into the error type registered in the
+	// client or the request, nil when there's none or it couldn't be decoded
+	Decoded interface{}
+	// Problem the RFC 7807 details of application/problem+json responses
+	Problem *ProblemDetails
+}
+
+func (e *HTTPError) Error() string {
+	message := fmt.Sprintf("%s %s: unexpected status %s", e.Method, e.URL, e.Status)
+	if e.Problem != nil && e.Problem.Title != "" {
+		message += ": " + e.Problem.Title
+		if e.Problem.Detail != "" {
+			message += ", " + e.Problem.Detail
+		}
+	}
+	return message
+}
+
+// NewHTTPError creates the error for the given failed response
+func NewHTTPError(method string, url string, response *Response) *HTTPError {
+	return &HTTPError{
+		Method:     method,
+		URL:        url,
+		Status:     response.Status,
+		StatusCode: response.StatusCode,
+		Headers:    response.Headers,
+		Body:       response.Body,
+	}
+}
+
+// IsNotFound returns true when err is an HTTPError with status 404
+func IsNotFound(err error) bool {
+	return hasStatus(err, func(code int) bool {
+		return code == http.StatusNotFound
+	})
+}
+
+// IsClientError returns true when err is an HTTPError with a 4xx status
+func IsClientError(err error) bool {
+	return hasStatus(err, func(code int) bool {
+		return code >= 400 && code < 500
+	})
+}
+
+// IsServerError returns true when err is an HTTPError with a 5xx status
+func IsServerError(err error) bool {
+	return hasStatus(err, func(code int) bool {
+		return code >= 500 && code < 600
+	})
+}
+
+// IsRetryable returns true when err is an HTTPError whose status means the same
+// request may succeed later: 408, 429, 502, 503 and 504
+func IsRetryable(err error) bool {
+	return hasStatus(err, func(code int) bool {
+		switch code {
+		case http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	})
+}
+
+func hasStatus(err error, match func(code int) bool) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	return match(httpErr.StatusCode)
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	newError := func(code int) error {
+		response := &Response{
+			Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+			StatusCode: code,
+		}
+		// wrapped to make sure the predicates look through the chain
+		return fmt.Errorf("calling upstream: %w", NewHTTPError(http.MethodGet, "http://localhost", response))
+	}
+
+	t.Run("TestErrorsAs", func(t *testing.T) {
+		var httpErr *HTTPError
+		if !errors.As(newError(http.StatusNotFound), &httpErr) {
+			t.Fatalf("it should support errors.As")
+		}
+
+		if httpErr.Method != http.MethodGet || httpErr.URL != "http://localhost" || httpErr.StatusCode != http.StatusNotFound {
+			t.Errorf("it should keep the request and response details")
+		}
+	})
+
+	t.Run("TestPredicates", func(t *testing.T) {
+		if !IsNotFound(newError(http.StatusNotFound)) || IsNotFound(newError(http.StatusBadRequest)) {
+			t.Errorf("IsNotFound doesnt match")
+		}
+
+		if !IsClientError(newError(http.StatusBadRequest)) || IsClientError(newError(http.StatusInternalServerError)) {
+			t.Errorf("IsClientError doesnt match")
+		}
+
+		if !IsServerError(newError(http.StatusInternalServerError)) || IsServerError(newError(http.StatusNotFound)) {
+			t.Errorf("IsServerError doesnt match")
+		}
+
+		if !IsRetryable(newError(http.StatusServiceUnavailable)) || IsRetryable(newError(http.StatusInternalServerError)) {
+			t.Errorf("IsRetryable doesnt match")
+		}
+
+		if IsNotFound(errors.New("plain error")) {
+			t.Errorf("predicates should ignore other errors")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProblemDetails(t *testing.T) {
+	body := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","balance":30}`
+
+	t.Run("TestUnmarshal", func(t *testing.T) {
+		var problem ProblemDetails
+		if err := json.Unmarshal([]byte(body), &problem); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if problem.Type != "https://example.com/probs/out-of-credit" || problem.Status != 403 || problem.Instance != "/account/12345/msgs/abc" {
+			t.Errorf("it should decode the standard members, got %+v", problem)
+		}
+
+		if problem.Extensions["balance"] != float64(30) || len(problem.Extensions) != 1 {
+			t.Errorf("it should keep the extension members, got %v", problem.Extensions)
+		}
+	})
+
+	t.Run("TestMarshalRoundTrip", func(t *testing.T) {
+		var problem ProblemDetails
+		json.Unmarshal([]byte(body), &problem)
+
+		encoded, err := json.Marshal(problem)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		var decoded map[string]interface{}
+		json.Unmarshal(encoded, &decoded)
+		if decoded["balance"] != float64(30) || decoded["title"] != problem.Title {
+			t.Errorf("it should encode extensions as top level members, got %s", encoded)
+		}
+	})
+
+	t.Run("TestInvalidMember", func(t *testing.T) {
+		var problem ProblemDetails
+		if err := json.Unmarshal([]byte(`{"status":"forbidden"}`), &problem); err == nil {
+			t.Errorf("it should reject an invalid status")
+		}
+	})
+}
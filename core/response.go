@@ -2,7 +2,10 @@ package core
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"net/http"
+
+	"github.com/andresmijares/goat-rest/mime"
 )
 
 // Response http parseable response used for all
@@ -12,6 +15,13 @@ type Response struct {
 	StatusCode int
 	Headers    http.Header
 	Body       []byte
+	// Truncated is true when the body was cut at the max response bytes of the client
+	Truncated bool
+	// CompressedSize bytes received before decompressing the body, the same as
+	// UncompressedSize when it wasn't compressed and -1 when unknown
+	CompressedSize int64
+	// UncompressedSize bytes read after decompressing the body
+	UncompressedSize int64
 }
 
 // Bytes returns bytes representation of the response
@@ -24,7 +34,34 @@ func (r *Response) String() string {
 	return string(r.Body)
 }
 
+// IsSuccess returns true for 2xx status codes
+func (r *Response) IsSuccess() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
 // UnmarshalJson used to parse custom structs with the response
 func (r *Response) UnmarshalJson(target interface{}) error {
 	return json.Unmarshal(r.Bytes(), target)
 }
+
+// UnmarshalXml used to parse custom structs with the response
+func (r *Response) UnmarshalXml(target interface{}) error {
+	return xml.Unmarshal(r.Bytes(), target)
+}
+
+// Unmarshal parses the response into target with the codec registered for the
+// response Content-Type, JSON is assumed when the header is missing
+func (r *Response) Unmarshal(target interface{}) error {
+	return r.UnmarshalWithOptions(target, mime.DecodeOptions{})
+}
+
+// UnmarshalWithOptions works like Unmarshal applying the strict mode options,
+// ex: mime.DecodeOptions{DisallowUnknownFields: true, UseNumber: true}, it fails
+// with a mime.ErrContentTypeMismatch when the body doesn't look like its content type
+func (r *Response) UnmarshalWithOptions(target interface{}, options mime.DecodeOptions) error {
+	contentType := r.Headers.Get(mime.HeaderContentType)
+	if contentType == "" {
+		contentType = mime.ApplicationTypeJSON
+	}
+	return mime.Unmarshal(contentType, r.Bytes(), target, options)
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+func TestResponseUnmarshal(t *testing.T) {
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	t.Run("TestUsesContentType", func(t *testing.T) {
+		response := Response{
+			Headers: http.Header{"Content-Type": {"application/xml; charset=utf-8"}},
+			Body:    []byte(`<item><name>goat</name></item>`),
+		}
+
+		var target item
+		if err := response.Unmarshal(&target); err != nil || target.Name != "goat" {
+			t.Errorf("it should decode xml, got %v", err)
+		}
+	})
+
+	t.Run("TestDefaultsToJSON", func(t *testing.T) {
+		response := Response{Body: []byte(`{"name":"goat"}`)}
+
+		var target item
+		if err := response.Unmarshal(&target); err != nil || target.Name != "goat" {
+			t.Errorf("it should decode json, got %v", err)
+		}
+	})
+
+	t.Run("TestUnknownContentType", func(t *testing.T) {
+		response := Response{
+			Headers: http.Header{"Content-Type": {"text/csv"}},
+			Body:    []byte(`name\ngoat`),
+		}
+
+		var target item
+		if err := response.Unmarshal(&target); err == nil {
+			t.Errorf("it should fail without a codec")
+		}
+	})
+
+	t.Run("TestUnmarshalXml", func(t *testing.T) {
+		response := Response{Body: []byte(`<item><name>goat</name></item>`)}
+
+		var target item
+		if err := response.UnmarshalXml(&target); err != nil || target.Name != "goat" {
+			t.Errorf("it should decode xml regardless of the headers, got %v", err)
+		}
+	})
+
+	t.Run("TestStrictOptions", func(t *testing.T) {
+		response := Response{
+			Headers: http.Header{"Content-Type": {"application/json"}},
+			Body:    []byte(`{"name":"goat","extra":true}`),
+		}
+
+		var target item
+		if err := response.UnmarshalWithOptions(&target, mime.DecodeOptions{DisallowUnknownFields: true}); err == nil {
+			t.Errorf("it should fail on unknown fields")
+		}
+	})
+
+	t.Run("TestContentTypeMismatch", func(t *testing.T) {
+		response := Response{
+			Headers: http.Header{"Content-Type": {"application/json"}},
+			Body:    []byte(`<html><body>502 Bad Gateway</body></html>`),
+		}
+
+		var target item
+		if err := response.Unmarshal(&target); !errors.Is(err, mime.ErrContentTypeMismatch) {
+			t.Errorf("it should report the mismatch, got %v", err)
+		}
+	})
+}
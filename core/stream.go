@@ -0,0 +1,20 @@
+package core
+
+import (
+	"io"
+	"net/http"
+)
+
+// StreamResponse http response whose body is read straight from the connection,
+// the caller must close the Body once done with it
+type StreamResponse struct {
+	Status     string
+	StatusCode int
+	Headers    http.Header
+	Body       io.ReadCloser
+}
+
+// IsSuccess returns true for 2xx status codes
+func (r *StreamResponse) IsSuccess() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
@@ -0,0 +1,171 @@
+package goat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+// tokenExpiryDelta tokens are refreshed this long before they expire, so they
+// don't expire while the request is on its way
+const tokenExpiryDelta = 10 * time.Second
+
+// tokenRefreshTimeout limits a refresh, it isn't tied to the context of the
+// request that started it since other requests wait for it too
+const tokenRefreshTimeout = 30 * time.Second
+
+// Token access token sent in the Authorization header
+type Token struct {
+	AccessToken string
+	// TokenType authorization scheme, Bearer when empty
+	TokenType string
+	// Expiry when the token expires, zero when it doesn't
+	Expiry time.Time
+}
+
+// Valid returns true when the token is set and isn't about to expire
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" &&
+		(t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+func (t *Token) authorization() string {
+	tokenType := t.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// TokenSource returns the tokens of the requests, the client caches them until
+// shortly before they expire so it's only called to get a new one
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// tokenCache keeps the current token, its zero value is ready to use
+type tokenCache struct {
+	mutex   sync.Mutex
+	token   *Token
+	refresh *tokenRefresh
+}
+
+// tokenRefresh a refresh in flight, done is closed once it finishes
+type tokenRefresh struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// get returns the cached token, refreshing it when it's about to expire, concurrent
+// callers wait for the same refresh instead of starting their own, every caller
+// stops waiting once its own context is done
+func (c *tokenCache) get(ctx context.Context, source TokenSource) (*Token, error) {
+	c.mutex.Lock()
+	if c.token.Valid() {
+		token := c.token
+		c.mutex.Unlock()
+		return token, nil
+	}
+
+	refresh := c.refresh
+	if refresh == nil {
+		refresh = &tokenRefresh{done: make(chan struct{})}
+		c.refresh = refresh
+		go c.run(refresh, source)
+	}
+	c.mutex.Unlock()
+
+	select {
+	case <-refresh.done:
+		return refresh.token, refresh.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run fetches a new token for every caller waiting on the refresh
+func (c *tokenCache) run(refresh *tokenRefresh, source TokenSource) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+	defer cancel()
+
+	refresh.token, refresh.err = source.Token(ctx)
+	if refresh.err == nil && refresh.token == nil {
+		refresh.err = fmt.Errorf("token source returned no token")
+	}
+
+	c.mutex.Lock()
+	if refresh.err == nil {
+		c.token = refresh.token
+	}
+	c.refresh = nil
+	c.mutex.Unlock()
+	close(refresh.done)
+}
+
+// invalidate drops the token unless it was already replaced by a newer one
+func (c *tokenCache) invalidate(token *Token) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.token == token {
+		c.token = nil
+	}
+}
+
+// sendAuthorized sends the request with the token of the token source, when the
+// server rejects it with a 401 the token is invalidated and the request retried
+// once with a new one, requests setting their own Authorization are sent as they are
+func (c *httpClient) sendAuthorized(ctx context.Context, client core.HttpClient, request *http.Request) (*http.Response, error) {
+	source := c.config.tokenSource
+	if source == nil || request.Header.Get("Authorization") != "" {
+		return c.send(ctx, client, request)
+	}
+
+	token, err := c.tokens.get(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get token: %w", err)
+	}
+
+	// the request belongs to the middlewares, calling next again must get a token too
+	retry := replayable(request)
+	authorized := request.Clone(ctx)
+	authorized.Header.Set("Authorization", token.authorization())
+
+	response, err := c.send(ctx, client, authorized)
+	if err != nil || response.StatusCode != http.StatusUnauthorized || !retry {
+		return response, err
+	}
+
+	// the token was rejected before its expiry, ex: it was revoked
+	c.tokens.invalidate(token)
+
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	token, err = c.tokens.get(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get token: %w", err)
+	}
+
+	retryRequest, err := cloneRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	retryRequest.Header.Set("Authorization", token.authorization())
+
+	return c.send(ctx, client, retryRequest)
+}
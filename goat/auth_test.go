@@ -0,0 +1,203 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+// countingSource issues numbered tokens expiring after ttl
+type countingSource struct {
+	calls int32
+	ttl   time.Duration
+	delay time.Duration
+	err   error
+}
+
+func (s *countingSource) Token(ctx context.Context) (*Token, error) {
+	calls := atomic.AddInt32(&s.calls, 1)
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	token := &Token{AccessToken: fmt.Sprintf("token-%d", calls)}
+	if s.ttl > 0 {
+		token.Expiry = time.Now().Add(s.ttl)
+	}
+	return token, nil
+}
+
+func TestTokenSource(t *testing.T) {
+	var revoked sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if _, ok := revoked.Load(authorization); ok || !strings.HasPrefix(authorization, "Bearer ") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(authorization))
+	}))
+	defer server.Close()
+
+	t.Run("TestCachesToken", func(t *testing.T) {
+		source := &countingSource{}
+		client := New().SetTokenSource(source).Create()
+
+		for i := 0; i < 3; i++ {
+			resp, err := client.Get(server.URL, nil)
+			if err != nil || resp.String() != "Bearer token-1" {
+				t.Fatalf("it should send the cached token, got %v", err)
+			}
+		}
+
+		if source.calls != 1 {
+			t.Errorf("it should fetch the token once, got %d", source.calls)
+		}
+	})
+
+	t.Run("TestRefreshesBeforeExpiry", func(t *testing.T) {
+		// expires within the expiry delta, so every request needs a new one
+		source := &countingSource{ttl: tokenExpiryDelta / 2}
+		client := New().SetTokenSource(source).Create()
+
+		client.Get(server.URL, nil)
+		resp, _ := client.Get(server.URL, nil)
+		if resp.String() != "Bearer token-2" || source.calls != 2 {
+			t.Errorf("it should refresh tokens about to expire, got %s", resp.String())
+		}
+	})
+
+	t.Run("TestSingleRefresh", func(t *testing.T) {
+		source := &countingSource{delay: 50 * time.Millisecond}
+		client := New().SetTokenSource(source).Create()
+
+		var group sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				if _, err := client.Get(server.URL, nil); err != nil {
+					t.Errorf("it should return nil error, got %v", err)
+				}
+			}()
+		}
+		group.Wait()
+
+		if source.calls != 1 {
+			t.Errorf("it should run a single refresh, got %d", source.calls)
+		}
+	})
+
+	t.Run("TestCanceledCallerDoesntFailRefresh", func(t *testing.T) {
+		source := &countingSource{delay: 50 * time.Millisecond}
+		client := New().SetTokenSource(source).Create()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		canceled := make(chan error, 1)
+		go func() {
+			_, err := client.GetWithContext(ctx, server.URL)
+			canceled <- err
+		}()
+
+		// waits on the refresh started by the canceled request
+		time.Sleep(10 * time.Millisecond)
+		result := make(chan error, 1)
+		go func() {
+			_, err := client.Get(server.URL)
+			result <- err
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		if err := <-canceled; !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should stop waiting on its own context, got %v", err)
+		}
+
+		if err := <-result; err != nil {
+			t.Errorf("it should get the token of the shared refresh, got %v", err)
+		}
+
+		if source.calls != 1 {
+			t.Errorf("it should run a single refresh, got %d", source.calls)
+		}
+	})
+
+	t.Run("TestRetriesOnUnauthorized", func(t *testing.T) {
+		source := &countingSource{}
+		client := New().SetTokenSource(source).Create()
+
+		client.Get(server.URL, nil)
+		revoked.Store("Bearer token-1", true)
+
+		resp, err := client.Post(server.URL, map[string]string{"name": "goat"})
+		if err != nil || resp.StatusCode != http.StatusOK || resp.String() != "Bearer token-2" {
+			t.Errorf("it should retry with a new token, got %v", err)
+		}
+
+		revoked.Store("Bearer token-2", true)
+		revoked.Store("Bearer token-3", true)
+		if resp, _ := client.Get(server.URL, nil); resp.StatusCode != http.StatusUnauthorized || source.calls != 3 {
+			t.Errorf("it should retry only once, got %d after %d tokens", resp.StatusCode, source.calls)
+		}
+	})
+
+	t.Run("TestMiddlewareCallsNextAgain", func(t *testing.T) {
+		// only the first token is rejected, once the middleware has seen it
+		var rejected int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Bearer token-1" && atomic.LoadInt32(&rejected) == 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+		defer server.Close()
+
+		source := &countingSource{}
+		client := New().
+			SetTokenSource(source).
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				if _, err := next(request); err != nil {
+					return nil, err
+				}
+				atomic.StoreInt32(&rejected, 1)
+				return next(request)
+			}).
+			Create()
+
+		resp, err := client.Get(server.URL, nil)
+		if err != nil || resp.StatusCode != http.StatusOK || resp.String() != "Bearer token-2" {
+			t.Errorf("it should authorize every call of next, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("TestSourceError", func(t *testing.T) {
+		sourceErr := errors.New("token server down")
+		client := New().SetTokenSource(&countingSource{err: sourceErr}).Create()
+
+		if _, err := client.Get(server.URL, nil); !errors.Is(err, sourceErr) {
+			t.Errorf("it should abort the request, got %v", err)
+		}
+	})
+
+	t.Run("TestOwnAuthorization", func(t *testing.T) {
+		source := &countingSource{}
+		client := New().SetTokenSource(source).Create()
+
+		resp, err := client.Get(server.URL, http.Header{"Authorization": {"Bearer own"}})
+		if err != nil || resp.String() != "Bearer own" || source.calls != 0 {
+			t.Errorf("it should keep the request authorization, got %s", resp.String())
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package goat
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+// requestBody encoded body ready to be attached to the outgoing request
+type requestBody struct {
+	reader io.Reader
+	// length in bytes, -1 when unknown
+	length int64
+	// getBody returns a fresh copy of the body for retries, nil when it can't be replayed
+	getBody func() (io.ReadCloser, error)
+	// contentType set when the body defines its own content type, ex: multipart boundaries
+	contentType string
+	// defaultContentType used when the request doesn't declare a content type
+	defaultContentType string
+	// data the whole body when it's already in memory
+	data []byte
+}
+
+// bodyProvider implemented by the bodies that know how to stream themselves
+type bodyProvider interface {
+	requestBody() (*requestBody, error)
+}
+
+// newRequestBody encodes the body, streaming bodies and readers are used as
+// they are, anything else goes through the codec of the content type
+func (c *httpClient) newRequestBody(contentType string, body interface{}) (*requestBody, error) {
+	switch b := body.(type) {
+	case bodyProvider:
+		return b.requestBody()
+	case io.Reader:
+		return readerBody(b), nil
+	}
+
+	data, err := c.getRequestBody(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return bytesBody(data), nil
+}
+
+func bytesBody(data []byte) *requestBody {
+	body := &requestBody{
+		reader: bytes.NewReader(data),
+		length: int64(len(data)),
+		data:   data,
+		getBody: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+	if len(data) > 0 {
+		body.defaultContentType = mime.ApplicationTypeJSON
+	}
+	return body
+}
+
+// attach sets the body on the request the same way http.NewRequest does for buffers
+func (b *requestBody) attach(request *http.Request) {
+	if b == nil || b.length == 0 {
+		request.Body = http.NoBody
+		request.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		request.ContentLength = 0
+		return
+	}
+
+	request.Body = toReadCloser(b.reader)
+	request.GetBody = b.getBody
+	request.ContentLength = b.length
+}
+
+// replayable returns true when the request body can be sent again
+func replayable(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+// lazyPipeReader starts writing into the pipe on the first read, so bodies
+// that are never sent don't leak the writing goroutine
+type lazyPipeReader struct {
+	*io.PipeReader
+	once  sync.Once
+	start func()
+	// stop releases what the writer would have, when it's closed before the first read
+	stop func() error
+}
+
+func (r *lazyPipeReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	return r.PipeReader.Read(p)
+}
+
+func (r *lazyPipeReader) Close() error {
+	started := true
+	r.once.Do(func() { started = false })
+
+	err := r.PipeReader.Close()
+	if !started && r.stop != nil {
+		if stopErr := r.stop(); err == nil {
+			err = stopErr
+		}
+	}
+	return err
+}
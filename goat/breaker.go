@@ -0,0 +1,267 @@
+package goat
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerCoolDown         = 30 * time.Second
+	defaultBreakerHalfOpenProbes   = 1
+)
+
+// CircuitState state of the circuit breaker for a single host
+type CircuitState int
+
+const (
+	// StateClosed requests flow normally while failures are counted
+	StateClosed CircuitState = iota
+	// StateOpen requests fail fast until the cool down window ends
+	StateOpen
+	// StateHalfOpen a limited number of probe requests decide whether the circuit closes again
+	StateHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// outcome result of an attempt as seen by the circuit breaker
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored attempts that say nothing about the host health, ex: canceled by the caller
+	outcomeIgnored
+)
+
+// CircuitBreakerSettings configures the circuit breaker kept for every upstream host,
+// zero values fall back to defaults
+type CircuitBreakerSettings struct {
+	// FailureThreshold consecutive failures that open the circuit, defaults to 5
+	FailureThreshold int
+	// CoolDown how long the circuit stays open before allowing probes, defaults to 30s
+	CoolDown time.Duration
+	// HalfOpenProbes concurrent probe requests allowed while half-open, all of them
+	// must succeed to close the circuit, defaults to 1
+	HalfOpenProbes int
+	// IsFailure decides if an attempt counts as a failure, by default transport
+	// errors and 5xx responses do
+	IsFailure func(response *http.Response, err error) bool
+	// OnStateChange is called every time the circuit of a host changes its state
+	OnStateChange func(host string, from CircuitState, to CircuitState)
+}
+
+// CircuitOpenError is returned without contacting the host while its circuit is open,
+// errors.Is(err, ErrCircuitOpen) matches it
+type CircuitOpenError struct {
+	Host string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return ErrCircuitOpen.Error() + " for host " + e.Host
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// circuitBreakers keeps a circuit breaker per host, its zero value is ready to use
+type circuitBreakers struct {
+	mutex    sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func (b *circuitBreakers) get(host string) *circuitBreaker {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.breakers == nil {
+		b.breakers = make(map[string]*circuitBreaker)
+	}
+
+	breaker, ok := b.breakers[host]
+	if !ok {
+		breaker = &circuitBreaker{}
+		b.breakers[host] = breaker
+	}
+	return breaker
+}
+
+type circuitBreaker struct {
+	mutex      sync.Mutex
+	state      CircuitState
+	generation uint64 // incremented on every transition
+	failures   int
+	openedAt   time.Time
+	probes     int
+	successes  int
+}
+
+// allow returns the generation the request was admitted under, or false when it has to fail fast
+func (b *circuitBreaker) allow(settings *CircuitBreakerSettings, notify func(from, to CircuitState)) (uint64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == StateOpen {
+		if time.Since(b.openedAt) < settings.getCoolDown() {
+			return b.generation, false
+		}
+		b.setState(StateHalfOpen, notify)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probes >= settings.getHalfOpenProbes() {
+			return b.generation, false
+		}
+		b.probes++
+	}
+
+	return b.generation, true
+}
+
+// record updates the breaker with the outcome of a request admitted under the given generation
+func (b *circuitBreaker) record(generation uint64, result outcome, settings *CircuitBreakerSettings, notify func(from, to CircuitState)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// outcomes of requests admitted before the last transition are stale
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		switch result {
+		case outcomeSuccess:
+			b.failures = 0
+		case outcomeFailure:
+			b.failures++
+			if b.failures >= settings.getFailureThreshold() {
+				b.setState(StateOpen, notify)
+			}
+		}
+	case StateHalfOpen:
+		// ignored probes only free their slot for another probe
+		b.probes--
+		switch result {
+		case outcomeIgnored:
+			return
+		case outcomeFailure:
+			b.setState(StateOpen, notify)
+			return
+		}
+		b.successes++
+		if b.successes >= settings.getHalfOpenProbes() {
+			b.setState(StateClosed, notify)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state CircuitState, notify func(from, to CircuitState)) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+	notify(from, state)
+}
+
+// withCircuitBreaker runs the attempt through the breaker of the request host when one is configured
+func (c *httpClient) withCircuitBreaker(request *http.Request, attempt func() (*http.Response, error)) (*http.Response, error) {
+	settings := c.config.circuitBreaker
+	if settings == nil {
+		return attempt()
+	}
+
+	host := request.URL.Host
+	breaker := c.breakers.get(host)
+
+	// transitions are collected while the breaker is locked and reported afterwards
+	// so the callback can safely use the client
+	var transitions [][2]CircuitState
+	notify := func(from, to CircuitState) {
+		transitions = append(transitions, [2]CircuitState{from, to})
+	}
+	report := func() {
+		if settings.OnStateChange != nil {
+			for _, t := range transitions {
+				settings.OnStateChange(host, t[0], t[1])
+			}
+		}
+		transitions = nil
+	}
+
+	generation, ok := breaker.allow(settings, notify)
+	report()
+	if !ok {
+		return nil, &CircuitOpenError{Host: host}
+	}
+
+	response, err := attempt()
+
+	breaker.record(generation, settings.outcome(request, response, err), settings, notify)
+	report()
+
+	return response, err
+}
+
+func (s *CircuitBreakerSettings) outcome(request *http.Request, response *http.Response, err error) outcome {
+	// requests abandoned by the caller say nothing about the host health
+	if request.Context().Err() != nil {
+		return outcomeIgnored
+	}
+
+	if s.isFailure(response, err) {
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
+
+func (s *CircuitBreakerSettings) isFailure(response *http.Response, err error) bool {
+	if s.IsFailure != nil {
+		return s.IsFailure(response, err)
+	}
+
+	if err != nil {
+		return true
+	}
+	return response.StatusCode >= http.StatusInternalServerError
+}
+
+func (s *CircuitBreakerSettings) getFailureThreshold() int {
+	if s.FailureThreshold > 0 {
+		return s.FailureThreshold
+	}
+	return defaultBreakerFailureThreshold
+}
+
+func (s *CircuitBreakerSettings) getCoolDown() time.Duration {
+	if s.CoolDown > 0 {
+		return s.CoolDown
+	}
+	return defaultBreakerCoolDown
+}
+
+func (s *CircuitBreakerSettings) getHalfOpenProbes() int {
+	if s.HalfOpenProbes > 0 {
+		return s.HalfOpenProbes
+	}
+	return defaultBreakerHalfOpenProbes
+}
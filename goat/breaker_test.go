@@ -0,0 +1,193 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("TestOpensAfterThreshold", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := New().
+			SetCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 2, CoolDown: time.Minute}).
+			Create()
+
+		client.Get(server.URL)
+		client.Get(server.URL)
+
+		_, err := client.Get(server.URL)
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("it should fail fast with an open circuit, got %v", err)
+		}
+
+		var openErr *CircuitOpenError
+		if !errors.As(err, &openErr) || openErr.Host == "" {
+			t.Errorf("it should report the host of the open circuit")
+		}
+
+		if attempts != 2 {
+			t.Errorf("it should not reach the server while open, got %d attempts", attempts)
+		}
+	})
+
+	t.Run("TestHalfOpenProbeClosesCircuit", func(t *testing.T) {
+		var healthy int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&healthy) == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		var mutex sync.Mutex
+		var transitions []string
+		client := New().
+			SetCircuitBreaker(CircuitBreakerSettings{
+				FailureThreshold: 1,
+				CoolDown:         10 * time.Millisecond,
+				OnStateChange: func(host string, from, to CircuitState) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					transitions = append(transitions, from.String()+">"+to.String())
+				},
+			}).
+			Create()
+
+		client.Get(server.URL)
+		atomic.StoreInt32(&healthy, 1)
+		time.Sleep(20 * time.Millisecond)
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Errorf("it should let the probe through, got %v", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("it should return status code 200")
+		}
+
+		expected := []string{"closed>open", "open>half-open", "half-open>closed"}
+		if len(transitions) != len(expected) {
+			t.Fatalf("it should report %v, got %v", expected, transitions)
+		}
+		for i := range expected {
+			if transitions[i] != expected[i] {
+				t.Errorf("it should report %v, got %v", expected, transitions)
+			}
+		}
+	})
+
+	t.Run("TestFailedProbeReopens", func(t *testing.T) {
+		settings := &CircuitBreakerSettings{FailureThreshold: 1, CoolDown: time.Millisecond}
+		breaker := &circuitBreaker{}
+		notify := func(from, to CircuitState) {}
+
+		generation, _ := breaker.allow(settings, notify)
+		breaker.record(generation, outcomeFailure, settings, notify)
+		time.Sleep(2 * time.Millisecond)
+
+		generation, ok := breaker.allow(settings, notify)
+		if !ok || breaker.state != StateHalfOpen {
+			t.Fatalf("it should admit a probe once cooled down")
+		}
+
+		if _, ok := breaker.allow(settings, notify); ok {
+			t.Errorf("it should admit a single probe at a time")
+		}
+
+		breaker.record(generation, outcomeFailure, settings, notify)
+		if breaker.state != StateOpen {
+			t.Errorf("it should reopen after a failed probe")
+		}
+	})
+
+	t.Run("TestSuccessResetsFailures", func(t *testing.T) {
+		settings := &CircuitBreakerSettings{FailureThreshold: 2}
+		breaker := &circuitBreaker{}
+		notify := func(from, to CircuitState) {}
+
+		generation, _ := breaker.allow(settings, notify)
+		breaker.record(generation, outcomeFailure, settings, notify)
+		breaker.record(generation, outcomeSuccess, settings, notify)
+		breaker.record(generation, outcomeFailure, settings, notify)
+
+		if breaker.state != StateClosed {
+			t.Errorf("it should only count consecutive failures")
+		}
+	})
+
+	t.Run("TestCanceledAttemptsAreIgnored", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(50 * time.Millisecond):
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		var mutex sync.Mutex
+		var transitions []CircuitState
+		client := New().
+			SetCircuitBreaker(CircuitBreakerSettings{
+				FailureThreshold: 1,
+				CoolDown:         time.Millisecond,
+				OnStateChange: func(host string, from CircuitState, to CircuitState) {
+					mutex.Lock()
+					transitions = append(transitions, to)
+					mutex.Unlock()
+				},
+			}).
+			Create()
+
+		client.Get(server.URL)
+		time.Sleep(2 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		if _, err := client.GetWithContext(ctx, server.URL); !errors.Is(err, ErrRequestCanceled) {
+			t.Fatalf("it should cancel the probe, got %v", err)
+		}
+
+		mutex.Lock()
+		got := append([]CircuitState(nil), transitions...)
+		mutex.Unlock()
+		if len(got) != 2 || got[0] != StateOpen || got[1] != StateHalfOpen {
+			t.Errorf("it should stay half-open after a canceled probe, got %v", got)
+		}
+
+		// the slot of the canceled probe is free again
+		if resp, err := client.Get(server.URL); err != nil || resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("it should admit another probe, got %v", err)
+		}
+	})
+
+	t.Run("TestIgnoredKeepsFailures", func(t *testing.T) {
+		settings := &CircuitBreakerSettings{FailureThreshold: 2}
+		breaker := &circuitBreaker{}
+		notify := func(from, to CircuitState) {}
+
+		generation, _ := breaker.allow(settings, notify)
+		breaker.record(generation, outcomeFailure, settings, notify)
+		breaker.record(generation, outcomeIgnored, settings, notify)
+		breaker.record(generation, outcomeFailure, settings, notify)
+
+		if breaker.state != StateOpen {
+			t.Errorf("it should not reset the failures on ignored attempts")
+		}
+	})
+}
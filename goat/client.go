@@ -1,6 +1,7 @@
 package goat
 
 import (
+	"context"
 	"net/http"
 	"sync"
 
@@ -14,34 +15,77 @@ type Client interface {
 	Patch(url string, body interface{}, headers ...http.Header) (*core.Response, error)
 	Delete(url string, headers ...http.Header) (*core.Response, error)
 	Options(url string, headers ...http.Header) (*core.Response, error)
+
+	// Context aware variants, the context is attached to the outgoing request
+	// so cancellation and deadlines reach the transport
+	GetWithContext(ctx context.Context, url string, headers ...http.Header) (*core.Response, error)
+	PostWithContext(ctx context.Context, url string, body interface{}, headers ...http.Header) (*core.Response, error)
+	PutWithContext(ctx context.Context, url string, body interface{}, headers ...http.Header) (*core.Response, error)
+	PatchWithContext(ctx context.Context, url string, body interface{}, headers ...http.Header) (*core.Response, error)
+	DeleteWithContext(ctx context.Context, url string, headers ...http.Header) (*core.Response, error)
+	OptionsWithContext(ctx context.Context, url string, headers ...http.Header) (*core.Response, error)
+
+	// R creates a request builder for per request options like query and path params,
+	// timeouts or result targets
+	R() *Request
 }
 
 type httpClient struct{
 	config *config
 	client core.HttpClient
 	clientOnce sync.Once
+	streamClient core.HttpClient
+	streamOnce sync.Once
+
+	breakers circuitBreakers
+	limiters rateLimiters
+	tokens   tokenCache
 }
 
 func (c *httpClient) Get(url string, headers ...http.Header) (*core.Response, error) {
-	return c.do(http.MethodGet, url, getHeaders(headers...), nil)
+	return c.do(context.Background(), http.MethodGet, url, getHeaders(headers...), nil)
 }
 
 func (c *httpClient) Post(url string, body interface{}, headers ...http.Header) (*core.Response, error) {
-	return c.do(http.MethodPost, url, getHeaders(headers...), body)
+	return c.do(context.Background(), http.MethodPost, url, getHeaders(headers...), body)
 }
 
 func (c *httpClient) Put(url string, body interface{}, headers ...http.Header) (*core.Response, error) {
-	return c.do(http.MethodPut, url, getHeaders(headers...), body)
+	return c.do(context.Background(), http.MethodPut, url, getHeaders(headers...), body)
 }
 
 func (c *httpClient) Patch(url string, body interface{}, headers ...http.Header) (*core.Response, error) {
-	return c.do(http.MethodPatch, url, getHeaders(headers...), body)
+	return c.do(context.Background(), http.MethodPatch, url, getHeaders(headers...), body)
 }
 
 func (c *httpClient) Delete(url string, headers ...http.Header) (*core.Response, error) {
-	return c.do(http.MethodDelete, url, getHeaders(headers...), nil)
+	return c.do(context.Background(), http.MethodDelete, url, getHeaders(headers...), nil)
 }
 
 func (c *httpClient) Options(url string, headers ...http.Header) (*core.Response, error) {
-	return c.do(http.MethodOptions, url, getHeaders(headers...), nil)
+	return c.do(context.Background(), http.MethodOptions, url, getHeaders(headers...), nil)
+}
+
+func (c *httpClient) GetWithContext(ctx context.Context, url string, headers ...http.Header) (*core.Response, error) {
+	return c.do(ctx, http.MethodGet, url, getHeaders(headers...), nil)
+}
+
+func (c *httpClient) PostWithContext(ctx context.Context, url string, body interface{}, headers ...http.Header) (*core.Response, error) {
+	return c.do(ctx, http.MethodPost, url, getHeaders(headers...), body)
+}
+
+func (c *httpClient) PutWithContext(ctx context.Context, url string, body interface{}, headers ...http.Header) (*core.Response, error) {
+	return c.do(ctx, http.MethodPut, url, getHeaders(headers...), body)
+}
+
+func (c *httpClient) PatchWithContext(ctx context.Context, url string, body interface{}, headers ...http.Header) (*core.Response, error) {
+	return c.do(ctx, http.MethodPatch, url, getHeaders(headers...), body)
+}
+
+func (c *httpClient) DeleteWithContext(ctx context.Context, url string, headers ...http.Header) (*core.Response, error) {
+	return c.do(ctx, http.MethodDelete, url, getHeaders(headers...), nil)
+}
+
+func (c *httpClient) OptionsWithContext(ctx context.Context, url string, headers ...http.Header) (*core.Response, error) {
+	return c.do(ctx, http.MethodOptions, url, getHeaders(headers...), nil)
 }
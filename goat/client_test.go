@@ -1 +1,96 @@
 package goat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andresmijares/goat-rest/goat_mock"
+)
+
+func TestWithContext(t *testing.T) {
+	t.Run("TestCanceledContext", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer server.Close()
+
+		client := New().Create()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		_, err := client.GetWithContext(ctx, server.URL)
+		if !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should return a canceled error, got %v", err)
+		}
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("it should unwrap to context.Canceled")
+		}
+	})
+
+	t.Run("TestDeadlineExceeded", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer server.Close()
+
+		client := New().Create()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.PostWithContext(ctx, server.URL, map[string]string{"foo": "bar"})
+		if !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should return a canceled error, got %v", err)
+		}
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("it should unwrap to context.DeadlineExceeded")
+		}
+	})
+
+	t.Run("TestMockHonorsContext", func(t *testing.T) {
+		url := "http://127.0.0.1/context"
+		goat_mock.MockupServer.Start()
+		goat_mock.MockupServer.Flush()
+		goat_mock.MockupServer.Add(goat_mock.Mock{
+			Method:             http.MethodGet,
+			URL:                url,
+			ResponseStatusCode: 200,
+		})
+		defer goat_mock.MockupServer.Stop()
+
+		client := New().Create()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.GetWithContext(ctx, url)
+		if !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should return a canceled error, got %v", err)
+		}
+
+		resp, err := client.GetWithContext(context.Background(), url)
+		if err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if resp.StatusCode != 200 {
+			t.Errorf("it should return status code 200")
+		}
+	})
+}
@@ -0,0 +1,378 @@
+package goat
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+// minRatioCheckBytes decompressed bytes read before the max ratio is enforced,
+// tiny bodies compress a lot better than real payloads
+const minRatioCheckBytes = 64 << 10
+
+// Decompressor wraps a reader of a content encoding with one that decodes it,
+// ex: for zstd
+//
+//	goat.RegisterDecompressor("zstd", func(r io.Reader) (io.ReadCloser, error) {
+//		decoder, err := zstd.NewReader(r)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return decoder.IOReadCloser(), nil
+//	})
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMutex sync.RWMutex
+	decompressors      = map[string]Decompressor{
+		"gzip":    newGzipReader,
+		"x-gzip":  newGzipReader,
+		"deflate": newDeflateReader,
+	}
+)
+
+// RegisterDecompressor registers the decompressor of a content encoding, replacing
+// any previous one, gzip and deflate are built in
+func RegisterDecompressor(encoding string, decompressor Decompressor) {
+	decompressorsMutex.Lock()
+	defer decompressorsMutex.Unlock()
+	decompressors[strings.ToLower(encoding)] = decompressor
+}
+
+func lookupDecompressor(encoding string) (Decompressor, bool) {
+	decompressorsMutex.RLock()
+	defer decompressorsMutex.RUnlock()
+	decompressor, ok := decompressors[strings.ToLower(strings.TrimSpace(encoding))]
+	return decompressor, ok
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// newDeflateReader reads zlib wrapped data, as the spec says, falling back to
+// the raw deflate some servers send
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decompress replaces the response body with its decoded content when the
+// Content-Encoding has a registered decompressor, it returns the counter of
+// the compressed bytes read, nil when the body isn't decoded
+func (c *httpClient) decompress(response *http.Response) (*countingReader, error) {
+	contentEncoding := response.Header.Get(mime.HeaderContentEncoding)
+	if contentEncoding == "" || !hasBody(response) {
+		return nil, nil
+	}
+
+	// encodings are listed in the order they were applied
+	encodings := strings.Split(contentEncoding, ",")
+	for _, encoding := range encodings {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if _, ok := lookupDecompressor(encoding); ok || encoding == "identity" {
+			continue
+		}
+
+		// the body is left as it is unless we asked for that encoding
+		if c.config.acceptsEncoding(encoding) {
+			response.Body.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+		}
+		return nil, nil
+	}
+
+	compressed := &countingReader{reader: response.Body}
+	decoder := &lazyDecoder{source: compressed, encodings: encodings}
+
+	// the same as the transport does with the gzip responses it decodes
+	response.Body = &decodedBody{
+		Reader:  &ratioReader{reader: decoder, compressed: compressed, maxRatio: c.config.maxDecompressionRatio},
+		closers: []io.Closer{response.Body, decoder},
+	}
+	response.Header.Del(mime.HeaderContentEncoding)
+	response.Header.Del(mime.HeaderContentLength)
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return compressed, nil
+}
+
+// hasBody tells if the response can carry a body to decode, HEAD, 204 and 304
+// responses keep the headers of the content they don't send
+func hasBody(response *http.Response) bool {
+	if response.Request != nil && response.Request.Method == http.MethodHead {
+		return false
+	}
+	switch response.StatusCode {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+	return response.ContentLength != 0
+}
+
+// lazyDecoder builds the decoders on the first read, as most of them read the
+// encoding header right away, streams return before the body arrives
+type lazyDecoder struct {
+	source    io.Reader
+	encodings []string
+	reader    io.Reader
+	closers   []io.Closer
+	err       error
+}
+
+func (d *lazyDecoder) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		d.err = d.init()
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+func (d *lazyDecoder) init() error {
+	reader := d.source
+	for i := len(d.encodings) - 1; i >= 0; i-- {
+		decompressor, ok := lookupDecompressor(d.encodings[i])
+		if !ok {
+			continue
+		}
+
+		decoded, err := decompressor(reader)
+		if err == io.EOF {
+			// nothing was sent, an empty body
+			return io.EOF
+		}
+		if err != nil {
+			return fmt.Errorf("unable to decode %s response: %w", strings.TrimSpace(d.encodings[i]), err)
+		}
+		d.closers = append(d.closers, decoded)
+		reader = decoded
+	}
+
+	d.reader = reader
+	return nil
+}
+
+func (d *lazyDecoder) Close() error {
+	var err error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if closeErr := d.closers[i].Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// ratioReader fails once the decompressed bytes exceed maxRatio times the compressed ones
+type ratioReader struct {
+	reader       io.Reader
+	compressed   *countingReader
+	maxRatio     float64
+	decompressed int64
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.decompressed += int64(n)
+
+	if r.maxRatio > 0 && r.decompressed > minRatioCheckBytes &&
+		float64(r.decompressed) > r.maxRatio*float64(r.compressed.n) {
+		return n, fmt.Errorf("%w: over %g:1", ErrDecompressionRatio, r.maxRatio)
+	}
+	return n, err
+}
+
+// decodedBody closes the decoders along with the original body
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if closeErr := b.closers[i].Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// defaultCompressionEncoding used when RequestCompression doesn't set one
+const defaultCompressionEncoding = "gzip"
+
+// RequestCompression compresses the request bodies, ex:
+//
+//	client := goat.New().
+//		SetRequestCompression(goat.RequestCompression{MinSize: 1 << 10}).
+//		Create()
+//
+// Bodies already in memory are compressed once and can be replayed on retries,
+// streaming bodies are compressed while they're sent
+type RequestCompression struct {
+	// Encoding content encoding with a registered compressor, gzip by default
+	Encoding string
+	// MinSize bodies smaller than this are sent as they are, the ones with an
+	// unknown length are always compressed
+	MinSize int64
+}
+
+func (s *RequestCompression) getEncoding() string {
+	if s.Encoding != "" {
+		return strings.ToLower(s.Encoding)
+	}
+	return defaultCompressionEncoding
+}
+
+// Compressor wraps a writer with one that encodes what is written to it in a
+// content encoding, closing it flushes the encoded data
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+var (
+	compressorsMutex sync.RWMutex
+	compressors      = map[string]Compressor{
+		"gzip": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		"deflate": func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriter(w), nil
+		},
+	}
+)
+
+// RegisterCompressor registers the compressor of a content encoding, replacing
+// any previous one, gzip and deflate are built in
+func RegisterCompressor(encoding string, compressor Compressor) {
+	compressorsMutex.Lock()
+	defer compressorsMutex.Unlock()
+	compressors[strings.ToLower(encoding)] = compressor
+}
+
+func lookupCompressor(encoding string) (Compressor, error) {
+	compressorsMutex.RLock()
+	defer compressorsMutex.RUnlock()
+	compressor, ok := compressors[encoding]
+	if !ok {
+		return nil, fmt.Errorf("no compressor registered for encoding %q", encoding)
+	}
+	return compressor, nil
+}
+
+// compress returns the body compressed following the settings, or the same
+// body when it's empty or smaller than the min size
+func (b *requestBody) compress(settings *RequestCompression) (*requestBody, error) {
+	if b == nil || b.length == 0 || (b.length > 0 && b.length < settings.MinSize) {
+		return b, nil
+	}
+
+	compressor, err := lookupCompressor(settings.getEncoding())
+	if err != nil {
+		return nil, err
+	}
+
+	// in memory bodies are compressed once, keeping the exact length and the replay
+	if b.data != nil {
+		var buffer bytes.Buffer
+		writer, err := compressor(&buffer)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(b.data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		compressed := bytesBody(buffer.Bytes())
+		compressed.contentType = b.contentType
+		compressed.defaultContentType = b.defaultContentType
+		return compressed, nil
+	}
+
+	compressed := &requestBody{
+		reader:             compressedReader(b.reader, compressor),
+		length:             -1,
+		contentType:        b.contentType,
+		defaultContentType: b.defaultContentType,
+	}
+
+	if b.getBody != nil {
+		getBody := b.getBody
+		compressed.getBody = func() (io.ReadCloser, error) {
+			reader, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return compressedReader(reader, compressor), nil
+		}
+	}
+	return compressed, nil
+}
+
+// compressedReader compresses the reader through a pipe while it's read
+func compressedReader(reader io.Reader, compressor Compressor) io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+	body := &lazyPipeReader{
+		PipeReader: pipeReader,
+		start: func() {
+			go func() {
+				pipeWriter.CloseWithError(compressTo(pipeWriter, reader, compressor))
+			}()
+		},
+	}
+
+	// the transport closes the bodies it doesn't send, the source is closed instead
+	if closer, ok := reader.(io.Closer); ok {
+		body.stop = closer.Close
+	}
+	return body
+}
+
+func compressTo(w io.Writer, reader io.Reader, compressor Compressor) error {
+	// the source is closed the same way the transport closes the request bodies
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	writer, err := compressor(w)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
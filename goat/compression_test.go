@@ -0,0 +1,357 @@
+package goat
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "deflate":
+		writer = zlib.NewWriter(&buffer)
+	case "raw-deflate":
+		writer, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	writer.Write(data)
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func TestResponseDecompression(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"name":"goat"}`, 100))
+	acceptEncodings := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncodings <- r.Header.Get("Accept-Encoding")
+
+		encoding := strings.TrimPrefix(r.URL.Path, "/")
+		switch encoding {
+		case "reverse":
+			// registered by the test, the content is sent backwards
+			w.Header().Set("Content-Encoding", "reverse")
+			w.Write(reverse(payload))
+		case "raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(compress(t, encoding, payload))
+		case "bomb":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compress(t, "gzip", make([]byte, 1<<20)))
+		default:
+			w.Header().Set("Content-Encoding", encoding)
+			w.Write(compress(t, encoding, payload))
+		}
+	}))
+	defer server.Close()
+
+	RegisterDecompressor("reverse", func(r io.Reader) (io.ReadCloser, error) {
+		data, err := ioutil.ReadAll(r)
+		return ioutil.NopCloser(bytes.NewReader(reverse(data))), err
+	})
+
+	client := New().SetAcceptEncodings("reverse", "gzip", "deflate").Create()
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "reverse"} {
+		t.Run("TestDecode_"+encoding, func(t *testing.T) {
+			resp, err := client.Get(server.URL+"/"+encoding, nil)
+			if err != nil {
+				t.Fatalf("it should return nil error, got %v", err)
+			}
+
+			if accepted := <-acceptEncodings; accepted != "reverse, gzip, deflate" {
+				t.Errorf("it should advertise the encodings, got %s", accepted)
+			}
+
+			if !bytes.Equal(resp.Body, payload) || resp.Headers.Get("Content-Encoding") != "" {
+				t.Errorf("it should decode the body, got %q", resp.Body)
+			}
+
+			if resp.UncompressedSize != int64(len(payload)) || resp.CompressedSize <= 0 {
+				t.Errorf("it should expose the sizes, got %d and %d", resp.CompressedSize, resp.UncompressedSize)
+			}
+
+			if encoding != "reverse" && resp.CompressedSize >= resp.UncompressedSize {
+				t.Errorf("it should count the compressed bytes, got %d", resp.CompressedSize)
+			}
+		})
+	}
+
+	t.Run("TestMaxDecompressionRatio", func(t *testing.T) {
+		bombClient := New().SetAcceptEncodings("gzip").SetMaxDecompressionRatio(100).Create()
+
+		_, err := bombClient.Get(server.URL+"/bomb", nil)
+		<-acceptEncodings
+		if !errors.Is(err, ErrDecompressionRatio) {
+			t.Errorf("it should stop decompression bombs, got %v", err)
+		}
+
+		if _, err := bombClient.Get(server.URL+"/gzip", nil); err != nil {
+			t.Errorf("it should accept regular bodies, got %v", err)
+		}
+		<-acceptEncodings
+	})
+
+	t.Run("TestStream", func(t *testing.T) {
+		resp, err := client.R().Stream(http.MethodGet, server.URL+"/gzip")
+		<-acceptEncodings
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if body, _ := ioutil.ReadAll(resp.Body); !bytes.Equal(body, payload) {
+			t.Errorf("it should decode streamed bodies, got %q", body)
+		}
+	})
+}
+
+func TestUnsupportedEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write([]byte("zstd frames"))
+	}))
+	defer server.Close()
+
+	t.Run("TestAccepted", func(t *testing.T) {
+		client := New().SetAcceptEncodings("zstd", "gzip").Create()
+
+		if _, err := client.Get(server.URL); !errors.Is(err, ErrUnsupportedEncoding) {
+			t.Errorf("it should fail on an accepted encoding it can't decode, got %v", err)
+		}
+	})
+
+	t.Run("TestNotAccepted", func(t *testing.T) {
+		client := New().Create()
+
+		resp, err := client.Get(server.URL)
+		if err != nil || resp.String() != "zstd frames" || resp.Headers.Get("Content-Encoding") != "zstd" {
+			t.Errorf("it should leave the body as it is, got %v", err)
+		}
+	})
+}
+
+func TestEncodedResponsesWithoutBody(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Length", "42")
+		case r.URL.Path == "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/delayed":
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-time.After(2 * time.Second):
+			}
+			w.Write(compress(t, "gzip", []byte("delayed")))
+		}
+	}))
+	defer server.Close()
+
+	client := New().Create()
+
+	t.Run("TestHead", func(t *testing.T) {
+		resp, err := client.R().Execute(http.MethodHead, server.URL)
+		if err != nil || resp.StatusCode != http.StatusOK || len(resp.Body) != 0 {
+			t.Errorf("it should not decode HEAD responses, got %v", err)
+		}
+	})
+
+	t.Run("TestNoContent", func(t *testing.T) {
+		resp, err := client.Get(server.URL + "/empty")
+		if err != nil || resp.StatusCode != http.StatusNoContent {
+			t.Errorf("it should not decode 204 responses, got %v", err)
+		}
+	})
+
+	t.Run("TestDelayedStream", func(t *testing.T) {
+		// advertising the encoding, the transport would decode it by itself otherwise
+		client := New().SetAcceptEncodings("gzip").Create()
+
+		start := time.Now()
+		resp, err := client.R().Stream(http.MethodGet, server.URL+"/delayed")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("it should return before the body arrives, took %v", elapsed)
+		}
+		close(release)
+
+		if body, err := ioutil.ReadAll(resp.Body); err != nil || string(body) != "delayed" {
+			t.Errorf("it should decode the body once it arrives, got %q, %v", body, err)
+		}
+	})
+}
+
+func reverse(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed
+}
+
+// closableReader records whether it was closed
+type closableReader struct {
+	io.Reader
+	closed int32
+}
+
+func (r *closableReader) Close() error {
+	atomic.StoreInt32(&r.closed, 1)
+	return nil
+}
+
+type compressedRequest struct {
+	contentEncoding string
+	contentLength   int64
+	body            string
+}
+
+func TestRequestCompression(t *testing.T) {
+	requests := make(chan compressedRequest, 10)
+	var failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := compressedRequest{
+			contentEncoding: r.Header.Get("Content-Encoding"),
+			contentLength:   r.ContentLength,
+		}
+
+		var reader io.Reader = r.Body
+		if request.contentEncoding == "gzip" {
+			gzipReader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				requests <- request
+				return
+			}
+			reader = gzipReader
+		}
+		body, _ := ioutil.ReadAll(reader)
+		request.body = string(body)
+		requests <- request
+
+		if r.URL.Path == "/flaky" && atomic.AddInt32(&failures, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	batch := []string{strings.Repeat("event", 100)}
+	client := New().
+		SetRequestCompression(RequestCompression{MinSize: 100}).
+		SetRetryPolicy(RetryPolicy{MaxAttempts: 2, RetryNonIdempotent: true}).
+		Create()
+
+	t.Run("TestCompressesLargeBodies", func(t *testing.T) {
+		if _, err := client.Post(server.URL, batch); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		request := <-requests
+		if request.contentEncoding != "gzip" || request.body != `["`+batch[0]+`"]` {
+			t.Errorf("it should send the gzip body, got %+v", request)
+		}
+
+		if request.contentLength <= 0 || request.contentLength >= int64(len(request.body)) {
+			t.Errorf("it should send the compressed length, got %d", request.contentLength)
+		}
+	})
+
+	t.Run("TestSkipsSmallBodies", func(t *testing.T) {
+		if _, err := client.Post(server.URL, []string{"small"}); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if request := <-requests; request.contentEncoding != "" || request.body != `["small"]` {
+			t.Errorf("it should send small bodies as they are, got %+v", request)
+		}
+	})
+
+	t.Run("TestReplaysCompressedBody", func(t *testing.T) {
+		resp, err := client.Post(server.URL+"/flaky", batch)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("it should succeed on the second attempt, got %v", err)
+		}
+
+		first, second := <-requests, <-requests
+		if first.body != second.body || second.contentEncoding != "gzip" {
+			t.Errorf("it should replay the compressed body, got %+v and %+v", first, second)
+		}
+	})
+
+	t.Run("TestStreams", func(t *testing.T) {
+		body := &Stream{Reader: onlyReader{strings.NewReader("streamed")}}
+		if _, err := client.Put(server.URL, body); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if request := <-requests; request.contentEncoding != "gzip" || request.body != "streamed" || request.contentLength != -1 {
+			t.Errorf("it should compress streams while sending them, got %+v", request)
+		}
+	})
+
+	t.Run("TestClosesUnsentStream", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+
+		source := &closableReader{Reader: strings.NewReader("streamed")}
+		if _, err := client.Put(closed.URL, &Stream{Reader: source}); err == nil {
+			t.Fatalf("it should fail to connect")
+		}
+
+		if atomic.LoadInt32(&source.closed) != 1 {
+			t.Errorf("it should close the source of a body that was never sent")
+		}
+	})
+
+	t.Run("TestRequestOverride", func(t *testing.T) {
+		if _, err := client.R().DisableCompression().SetBody(batch).Post(server.URL); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if request := <-requests; request.contentEncoding != "" {
+			t.Errorf("it should not compress the request, got %s", request.contentEncoding)
+		}
+
+		_, err := New().Create().R().
+			SetCompression(RequestCompression{}).
+			SetBody([]string{"small"}).
+			Post(server.URL)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if request := <-requests; request.contentEncoding != "gzip" || request.body != `["small"]` {
+			t.Errorf("it should compress the request, got %+v", request)
+		}
+	})
+
+	t.Run("TestUnknownEncoding", func(t *testing.T) {
+		_, err := client.R().SetCompression(RequestCompression{Encoding: "unknown"}).SetBody(batch).Post(server.URL)
+		if err == nil {
+			t.Errorf("it should fail without a compressor")
+		}
+	})
+}
@@ -2,6 +2,9 @@ package goat
 
 import (
 	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -20,6 +23,38 @@ type Config interface {
 	SetHttpClient(c *http.Client) Config
 	// SetUserAgent allows setting a user agent for requests
 	SetUserAgent(agent string) Config
+	// SetRetryPolicy retries failed requests following the given policy, idempotent methods only unless RetryNonIdempotent is set
+	SetRetryPolicy(policy RetryPolicy) Config
+	// SetCircuitBreaker enables a circuit breaker per upstream host, requests fail fast with a CircuitOpenError while it's open
+	SetCircuitBreaker(settings CircuitBreakerSettings) Config
+	// SetRateLimit limits the requests per second, either global or per host, waiting for a free token by default
+	SetRateLimit(limit RateLimit) Config
+	// Use appends middlewares to the chain wrapping every request, the first one registered runs first
+	Use(middlewares ...Middleware) Config
+	// SetQueryParams sets default query params sent with every request unless the request sets them
+	SetQueryParams(params url.Values) Config
+	// SetBaseURL sets the url relative request urls are resolved against
+	SetBaseURL(baseURL string) Config
+	// EnableHTTPErrors returns a *core.HTTPError along with the response for non 2xx responses
+	EnableHTTPErrors(enable bool) Config
+	// SetErrorType decodes non 2xx response bodies into a new value of the given type, attached to the returned *core.HTTPError
+	SetErrorType(errorBody interface{}) Config
+	// SetMaxResponseBytes stops reading response bodies over limit bytes, returning a *ResponseTooLargeError
+	SetMaxResponseBytes(limit int64) Config
+	// TruncateLargeResponses returns the body cut at the max response bytes, marked as Truncated, instead of an error
+	TruncateLargeResponses(enable bool) Config
+	// SetAcceptEncodings advertises the content encodings accepted, responses are decompressed before filling the Body
+	SetAcceptEncodings(encodings ...string) Config
+	// SetMaxDecompressionRatio fails responses expanding over ratio times their compressed size
+	SetMaxDecompressionRatio(ratio float64) Config
+	// SetRequestCompression compresses request bodies over the settings min size, setting their Content-Encoding
+	SetRequestCompression(settings RequestCompression) Config
+	// SetHeaderMergeStrategy sets how a header set on both the client and the request is merged, the request overrides by default
+	SetHeaderMergeStrategy(header string, strategy HeaderMergeStrategy) Config
+	// AddHeaderProviders registers functions computing headers for every attempt right before it's sent
+	AddHeaderProviders(providers ...HeaderProvider) Config
+	// SetTokenSource sends the tokens of the source in the Authorization header, retrying once with a new token on 401
+	SetTokenSource(source TokenSource) Config
 	// Build Returns a HTTP Client interface, it should be you called after set all the custom configuration
 	Create() Client
 }
@@ -33,6 +68,27 @@ type config struct {
 	headers http.Header
 	client *http.Client
 	agent string
+
+	retryPolicy    *RetryPolicy
+	circuitBreaker *CircuitBreakerSettings
+	rateLimit      *RateLimit
+	middlewares    []Middleware
+	queryParams    url.Values
+	baseURL        string
+	httpErrors     bool
+	errorType      reflect.Type
+
+	maxResponseBytes  int64
+	truncateResponses bool
+
+	acceptEncodings       []string
+	maxDecompressionRatio float64
+	requestCompression    *RequestCompression
+
+	headerStrategies map[string]HeaderMergeStrategy
+	headerProviders  []HeaderProvider
+
+	tokenSource TokenSource
 }
 
 func New() Config {
@@ -90,4 +146,157 @@ func (c *config) SetMaxIdleConnections(connections int) Config {
 func (c *config) SetUserAgent(agent string) Config {
 	c.agent = agent
 	return c
-}
\ No newline at end of file
+}
+
+// SetRetryPolicy retries failed requests following the given policy,
+// only idempotent methods are retried unless RetryNonIdempotent is set
+func (c *config) SetRetryPolicy(policy RetryPolicy) Config {
+	c.retryPolicy = &policy
+	return c
+}
+
+// SetCircuitBreaker enables a circuit breaker per upstream host, requests
+// fail fast with a CircuitOpenError while the circuit of their host is open
+func (c *config) SetCircuitBreaker(settings CircuitBreakerSettings) Config {
+	c.circuitBreaker = &settings
+	return c
+}
+
+// SetRateLimit limits the requests per second with a token bucket, either global
+// or per host, requests wait for a free token unless NonBlocking is set
+func (c *config) SetRateLimit(limit RateLimit) Config {
+	c.rateLimit = &limit
+	return c
+}
+
+// Use appends middlewares to the chain wrapping every request, the first
+// middleware registered is the first one to see the request
+func (c *config) Use(middlewares ...Middleware) Config {
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// SetQueryParams sets default query params, ex: an api key, they are sent
+// with every request unless the url or the request already sets them
+func (c *config) SetQueryParams(params url.Values) Config {
+	c.queryParams = params
+	return c
+}
+
+// SetBaseURL sets the url relative request urls are resolved against, following
+// url.URL.ResolveReference semantics: with "https://api.com/v1/" the path "users"
+// resolves to "https://api.com/v1/users" while "/users" resolves to "https://api.com/users"
+func (c *config) SetBaseURL(baseURL string) Config {
+	c.baseURL = baseURL
+	return c
+}
+
+// EnableHTTPErrors returns a *core.HTTPError along with the response for non 2xx
+// responses, it's disabled by default so callers check the StatusCode themselves
+func (c *config) EnableHTTPErrors(enable bool) Config {
+	c.httpErrors = enable
+	return c
+}
+
+// SetErrorType decodes non 2xx response bodies into a new value of the type of
+// errorBody, ex: SetErrorType(&APIError{}), the decoded pointer is attached as
+// Decoded to the *core.HTTPError returned, registering a type enables http errors
+func (c *config) SetErrorType(errorBody interface{}) Config {
+	errorType := reflect.TypeOf(errorBody)
+	for errorType != nil && errorType.Kind() == reflect.Ptr {
+		errorType = errorType.Elem()
+	}
+	c.errorType = errorType
+	return c
+}
+
+// newErrorBody returns a pointer to a new value of the registered error type, if any
+func (c *config) newErrorBody() interface{} {
+	if c.errorType == nil {
+		return nil
+	}
+	return reflect.New(c.errorType).Interface()
+}
+
+func (c *config) returnsHTTPErrors() bool {
+	return c.httpErrors || c.errorType != nil
+}
+
+// SetMaxResponseBytes limits the size of the buffered response bodies, reading stops
+// once the limit is crossed and a *ResponseTooLargeError carrying the status and
+// headers is returned, zero or less means no limit, the default
+func (c *config) SetMaxResponseBytes(limit int64) Config {
+	c.maxResponseBytes = limit
+	return c
+}
+
+// TruncateLargeResponses returns the first max response bytes of a larger body
+// instead of failing, the response is marked as Truncated
+func (c *config) TruncateLargeResponses(enable bool) Config {
+	c.truncateResponses = enable
+	return c
+}
+
+// SetAcceptEncodings sends the encodings as the Accept-Encoding header, unless the
+// request sets its own, ex: SetAcceptEncodings("zstd", "gzip"), responses in any
+// encoding with a registered decompressor are decoded before filling the Body,
+// gzip and deflate are built in, see RegisterDecompressor for others, a response
+// in an accepted encoding without decompressor fails with ErrUnsupportedEncoding
+func (c *config) SetAcceptEncodings(encodings ...string) Config {
+	c.acceptEncodings = append([]string(nil), encodings...)
+	return c
+}
+
+// SetMaxDecompressionRatio fails with ErrDecompressionRatio once a decoded response
+// grows over ratio times its compressed size, ex: 100 for 100:1, the first 64KB
+// are never rejected, zero or less means no limit, the default
+func (c *config) SetMaxDecompressionRatio(ratio float64) Config {
+	c.maxDecompressionRatio = ratio
+	return c
+}
+
+// SetRequestCompression compresses the request bodies with the settings encoding,
+// gzip by default, bodies smaller than MinSize are sent as they are, requests can
+// override it with Request.SetCompression or Request.DisableCompression
+func (c *config) SetRequestCompression(settings RequestCompression) Config {
+	c.requestCompression = &settings
+	return c
+}
+
+// SetHeaderMergeStrategy sets the merge strategy of a header, ex: HeaderAppend to
+// send both the client and the request X-Forwarded-For values, every value of a
+// multi-valued header is kept whatever the strategy
+func (c *config) SetHeaderMergeStrategy(header string, strategy HeaderMergeStrategy) Config {
+	if c.headerStrategies == nil {
+		c.headerStrategies = make(map[string]HeaderMergeStrategy)
+	}
+	c.headerStrategies[http.CanonicalHeaderKey(header)] = strategy
+	return c
+}
+
+// AddHeaderProviders appends header providers, they run in order right before every
+// attempt is sent, after the middlewares, so retries get fresh values, their headers
+// replace the merged ones unless the header has another merge strategy
+func (c *config) AddHeaderProviders(providers ...HeaderProvider) Config {
+	c.headerProviders = append(c.headerProviders, providers...)
+	return c
+}
+
+// SetTokenSource authorizes every request with a token of the source, ex:
+// "Authorization: Bearer <token>", tokens are cached until shortly before they
+// expire and a 401 response invalidates the token and retries the request once
+// when its body can be replayed
+func (c *config) SetTokenSource(source TokenSource) Config {
+	c.tokenSource = source
+	return c
+}
+
+// acceptsEncoding returns true when the encoding is one of the accepted encodings
+func (c *config) acceptsEncoding(encoding string) bool {
+	for _, accepted := range c.acceptEncodings {
+		if strings.EqualFold(strings.TrimSpace(accepted), encoding) {
+			return true
+		}
+	}
+	return false
+}
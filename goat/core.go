@@ -1,10 +1,10 @@
 package goat
 
 import (
-	"bytes"
-	"encoding/json"
-	"encoding/xml"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -24,56 +24,178 @@ var (
 	errInvalidRequest = errors.New("unable to perform request")
 )
 
-func (c *httpClient) do(method string, url string, headers http.Header, body interface{}) (*core.Response, error) {
+// requestOptions per request settings, already resolved against the client ones
+type requestOptions struct {
+	// compression nil when request bodies aren't compressed
+	compression *RequestCompression
+	// maxResponseBytes zero or less when there's no limit
+	maxResponseBytes int64
+}
+
+func (c *httpClient) do(ctx context.Context, method string, url string, headers http.Header, body interface{}) (*core.Response, error) {
+	return c.R().SetContext(ctx).SetHeaders(headers).SetBody(body).Execute(method, url)
+}
+
+// execute builds the request and runs it through the middleware chain
+func (c *httpClient) execute(ctx context.Context, method string, url string, headers http.Header, body interface{}, options requestOptions) (*core.Response, error) {
+	request, err := c.newRequest(ctx, method, url, headers, body, options)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.createHttpClient()
+
+	return c.chain(func(request *http.Request) (*core.Response, error) {
+		return c.perform(client, request, options.maxResponseBytes)
+	})(request)
+}
+
+// newRequest merges the headers and encodes the body into the outgoing request
+func (c *httpClient) newRequest(ctx context.Context, method string, url string, headers http.Header, body interface{}, options requestOptions) (*http.Request, error) {
 	allHeaders := c.setHeaders(headers)
 
-	requestBody, err := c.getRequestBody(headers.Get(mime.HeaderContentType), body)
+	// the encoding follows the merged headers, so client level content types apply too
+	contentType := allHeaders.Get(mime.HeaderContentType)
+	requestBody, err := c.newRequestBody(contentType, body)
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest(method, url, bytes.NewBuffer(requestBody))
+	// bodies already encoded by the caller are sent as they are
+	if options.compression != nil && allHeaders.Get(mime.HeaderContentEncoding) == "" {
+		compressed, err := requestBody.compress(options.compression)
+		if err != nil {
+			return nil, err
+		}
+		if compressed != requestBody {
+			allHeaders.Set(mime.HeaderContentEncoding, options.compression.getEncoding())
+			requestBody = compressed
+		}
+	}
+
+	switch {
+	case requestBody.contentType != "":
+		allHeaders.Set(mime.HeaderContentType, requestBody.contentType)
+	case contentType == "" && requestBody.defaultContentType != "":
+		allHeaders.Set(mime.HeaderContentType, requestBody.defaultContentType)
+	}
+
+	// ask for responses in the same format we send, request only formats like
+	// forms aren't response formats
+	if (mime.IsJSON(contentType) || mime.IsXML(contentType)) && allHeaders.Get(mime.HeaderAccept) == "" {
+		allHeaders.Set(mime.HeaderAccept, contentType)
+	}
+
+	// the transport stops decoding gzip once the header is set, decompress does it instead
+	if len(c.config.acceptEncodings) > 0 && allHeaders.Get(mime.HeaderAcceptEncoding) == "" {
+		allHeaders.Set(mime.HeaderAcceptEncoding, strings.Join(c.config.acceptEncodings, ", "))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, errInvalidRequest
 	}
 
-	request.Header = allHeaders
+	requestBody.attach(request)
+	applyHeaders(request, allHeaders)
 
-	c.client = c.createHttpClient()
+	return request, nil
+}
+
+// perform sends the request and buffers its response, up to maxResponseBytes
+// when it's greater than zero, it's the innermost handler of the middleware chain
+func (c *httpClient) perform(client core.HttpClient, request *http.Request, maxResponseBytes int64) (*core.Response, error) {
+	ctx := request.Context()
 
-	response, err := c.client.Do(request)
+	response, err := c.sendAuthorized(ctx, client, request)
 	if err != nil {
-		return nil, err
+		return nil, contextError(ctx, err)
 	}
 	defer response.Body.Close()
 
-	responseBody, err := ioutil.ReadAll(response.Body)
+	compressed, err := c.decompress(response)
 	if err != nil {
-		return nil, err
+		return nil, contextError(ctx, err)
+	}
+
+	responseBody, truncated, err := c.readBody(response, maxResponseBytes)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	size := int64(len(responseBody))
+	compressedSize := size
+	switch {
+	case compressed != nil:
+		compressedSize = compressed.n
+	case response.Uncompressed:
+		// decoded by the transport itself
+		compressedSize = -1
 	}
 
 	return &core.Response{
+		Status:           response.Status,
+		StatusCode:       response.StatusCode,
+		Headers:          response.Header,
+		Body:             responseBody,
+		Truncated:        truncated,
+		CompressedSize:   compressedSize,
+		UncompressedSize: size,
+	}, nil
+}
+
+// readBody reads the whole body, never more than one byte over maxBytes so large
+// bodies are rejected, or truncated when enabled, without being held in memory
+func (c *httpClient) readBody(response *http.Response, maxBytes int64) ([]byte, bool, error) {
+	if maxBytes <= 0 {
+		body, err := ioutil.ReadAll(response.Body)
+		return body, false, err
+	}
+
+	tooLarge := &ResponseTooLargeError{
+		Limit:      maxBytes,
 		Status:     response.Status,
 		StatusCode: response.StatusCode,
 		Headers:    response.Header,
-		Body:       responseBody,
-	}, nil
+	}
+
+	// a declared length over the limit fails before reading anything
+	if response.ContentLength > maxBytes && !c.config.truncateResponses {
+		return nil, false, tooLarge
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if int64(len(body)) <= maxBytes {
+		return body, false, nil
+	}
+
+	if !c.config.truncateResponses {
+		return nil, false, tooLarge
+	}
+	return body[:maxBytes], true, nil
 }
 
+// setHeaders merges the client headers with the request ones keeping every value,
+// headers set on both follow their merge strategy, the request wins by default
 func (c *httpClient) setHeaders(requestHeader http.Header) http.Header {
 	h := make(http.Header)
 
 	// commons headers
-	for header, value := range c.config.headers {
-		if len(value) > 0 {
-			h.Set(header, value[0])
+	for header, values := range c.config.headers {
+		if len(values) > 0 {
+			h[http.CanonicalHeaderKey(header)] = append([]string(nil), values...)
 		}
 	}
 
 	// custom headers
-	for header, value := range requestHeader {
-		if len(value) > 0 {
-			h.Set(header, value[0])
+	for header, values := range requestHeader {
+		if len(values) > 0 {
+			header = http.CanonicalHeaderKey(header)
+			mergeHeaders(h, header, values, c.config.headerStrategies[header])
 		}
 	}
 
@@ -118,19 +240,39 @@ func (c *httpClient) createHttpClient() core.HttpClient {
 	return c.client
 }
 
+// createStreamClient returns the client without its overall timeout, which would
+// also cut the body read, the connection and response header timeouts still apply
+func (c *httpClient) createStreamClient() core.HttpClient {
+	client := c.createHttpClient()
+	timed, ok := client.(*http.Client)
+	if !ok || timed.Timeout == 0 {
+		return client
+	}
+
+	c.streamOnce.Do(func() {
+		// the copy shares the transport and its connections
+		untimed := *timed
+		untimed.Timeout = 0
+		c.streamClient = &untimed
+	})
+	return c.streamClient
+}
+
 func (c *httpClient) getRequestBody(contentType string, body interface{}) ([]byte, error) {
 	if body == nil {
 		return nil, nil
 	}
 
-	switch strings.ToLower(contentType) {
-	case mime.ApplicationTypeJSON:
-		return json.Marshal(body)
-	case mime.ApplicationTypeXML:
-		return xml.Marshal(body)
-	default:
-		return json.Marshal(body)
+	// JSON is the default encoding when there's no declared content type
+	if contentType == "" {
+		contentType = mime.ApplicationTypeJSON
+	}
+
+	codec, err := mime.LookupCodec(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode request body: %w", err)
 	}
+	return codec.Marshal(body)
 }
 
 /***
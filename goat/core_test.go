@@ -1,8 +1,11 @@
 package goat
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -22,7 +25,7 @@ func TestDo(t *testing.T) {
 		unmarshable := map[string]interface{}{
 			"foo": make(chan int),
 		}
-		_, err := client.do(http.MethodGet, "http://localhost", nil, unmarshable)
+		_, err := client.do(context.Background(), http.MethodGet, "http://localhost", nil, unmarshable)
 		if err == nil {
 			t.Errorf("Should return malformed marshal json")
 		}
@@ -35,7 +38,7 @@ func TestDo(t *testing.T) {
 			config: &cfg,
 		}
 
-		_, err := client.do("[0:0]", "http://localhost", nil, nil)
+		_, err := client.do(context.Background(), "[0:0]", "http://localhost", nil, nil)
 		if err != errInvalidRequest {
 			t.Errorf("Should return invalid http method")
 		}
@@ -48,7 +51,7 @@ func TestDo(t *testing.T) {
 			config: &cfg,
 		}
 
-		_, err := client.do(http.MethodPost, "http://localhost", nil, nil)
+		_, err := client.do(context.Background(), http.MethodPost, "http://localhost", nil, nil)
 		if !strings.Contains(err.Error(), "connection refused") {
 			t.Errorf("Should return connection refuse")
 		}
@@ -79,7 +82,7 @@ func TestDo(t *testing.T) {
 			"foo": "bar",
 		}
 
-		_, err := client.do(http.MethodPost, url, nil, payload)
+		_, err := client.do(context.Background(), http.MethodPost, url, nil, payload)
 		if err.Error() != "Invalid client response" {
 			t.Errorf("Should return bad response from client")
 		}
@@ -111,7 +114,7 @@ func TestDo(t *testing.T) {
 	// 		"foo": "bar",
 	// 	}
 
-	// 	_, err := client.do(http.MethodPost, url, nil, payload)
+	// 	_, err := client.do(context.Background(), http.MethodPost, url, nil, payload)
 	// 	fmt.Print("err", err)
 	// 	if err.Error() == "invalid json response" {
 	// 		t.Errorf("Should return unparsable response")
@@ -143,7 +146,7 @@ func TestDo(t *testing.T) {
 			"foo": "bar",
 		}
 
-		resp, err := client.do(http.MethodPost, url, nil, payload)
+		resp, err := client.do(context.Background(), http.MethodPost, url, nil, payload)
 		if err != nil {
 			t.Errorf("Should return nil error")
 		}
@@ -316,13 +319,80 @@ func TestGetRequestBody(t *testing.T) {
 
 	t.Run("TestBodyIsJSONByDefault", func(t *testing.T) {
 		requestBody := []string{"c"}
-		customBody, err := client.getRequestBody("application/customType", requestBody)
+		customBody, err := client.getRequestBody("", requestBody)
 		if err != nil {
 			t.Errorf("it should return nil error")
 		}
 
 		if string(customBody) != `["c"]` {
-			t.Errorf("it should return a json given a custom application type")
+			t.Errorf("it should return a json given no content type")
+		}
+	})
+
+	t.Run("TestBodyIsForm", func(t *testing.T) {
+		requestBody := map[string]string{"grant_type": "client_credentials"}
+		formBody, err := client.getRequestBody("application/x-www-form-urlencoded", requestBody)
+		if err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if string(formBody) != `grant_type=client_credentials` {
+			t.Errorf("it should return a form body")
+		}
+	})
+
+	t.Run("TestBodyUnknownContentType", func(t *testing.T) {
+		requestBody := []string{"c"}
+		_, err := client.getRequestBody("application/customType", requestBody)
+		if !errors.Is(err, mime.ErrNoCodec) {
+			t.Errorf("it should return a no codec error given a custom application type")
+		}
+	})
+
+	t.Run("TestBodyFollowsClientContentType", func(t *testing.T) {
+		var received string
+		var accept string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			received = string(body)
+			accept = r.Header.Get(mime.HeaderAccept)
+		}))
+		defer server.Close()
+
+		headers := make(http.Header)
+		headers.Set(mime.HeaderContentType, mime.ApplicationTypeXML)
+		client := New().SetHeaders(headers).Create()
+
+		if _, err := client.Post(server.URL, []string{"a"}); err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if received != `<string>a</string>` {
+			t.Errorf("it should encode with the client content type, got %s", received)
+		}
+
+		if accept != mime.ApplicationTypeXML {
+			t.Errorf("it should accept the same content type, got %s", accept)
+		}
+	})
+
+	t.Run("TestFormIsNotAccepted", func(t *testing.T) {
+		var accept string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept = r.Header.Get(mime.HeaderAccept)
+		}))
+		defer server.Close()
+
+		headers := make(http.Header)
+		headers.Set(mime.HeaderContentType, mime.ApplicationTypeForm)
+		client := New().Create()
+
+		if _, err := client.Post(server.URL, map[string]string{"a": "b"}, headers); err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if accept != "" {
+			t.Errorf("it should not accept request only formats, got %s", accept)
 		}
 	})
 }
@@ -0,0 +1,77 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrRequestCanceled is matched by errors.Is when a request was aborted
+	// because its context was canceled or its deadline expired
+	ErrRequestCanceled = errors.New("request canceled")
+	// ErrCircuitOpen is matched by errors.Is when a request failed fast because
+	// the circuit breaker of its host is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrRateLimited is matched by errors.Is when a non blocking rate limit
+	// rejected the request before sending it
+	ErrRateLimited = errors.New("rate limited locally")
+	// ErrMissingPathParam is returned before sending a request whose url still has
+	// {param} placeholders without a value
+	ErrMissingPathParam = errors.New("missing path param")
+	// ErrResponseTooLarge is matched by errors.Is when the response body exceeds
+	// the max response bytes and truncation isn't enabled
+	ErrResponseTooLarge = errors.New("response body too large")
+	// ErrDecompressionRatio is matched by errors.Is when a compressed response
+	// expands over the max decompression ratio, likely a decompression bomb
+	ErrDecompressionRatio = errors.New("max decompression ratio exceeded")
+	// ErrUnsupportedEncoding is returned when a response uses an accepted encoding
+	// without a registered decompressor
+	ErrUnsupportedEncoding = errors.New("no decompressor registered for content encoding")
+)
+
+// CanceledError is returned when the request context ends before the
+// response is fully read, it unwraps to the context error so both
+// errors.Is(err, ErrRequestCanceled) and errors.Is(err, context.Canceled) work
+type CanceledError struct {
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return ErrRequestCanceled.Error() + ": " + e.Err.Error()
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CanceledError) Is(target error) bool {
+	return target == ErrRequestCanceled
+}
+
+// contextError returns a CanceledError when the context is already done,
+// otherwise it returns the original error untouched
+func contextError(ctx context.Context, err error) error {
+	if ctx == nil || ctx.Err() == nil {
+		return err
+	}
+	return &CanceledError{Err: ctx.Err()}
+}
+
+// ResponseTooLargeError is returned when the response body exceeds the max response
+// bytes, the body is discarded but the status and headers are kept
+type ResponseTooLargeError struct {
+	Limit      int64
+	Status     string
+	StatusCode int
+	Headers    http.Header
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s: over %d bytes (status %d)", ErrResponseTooLarge, e.Limit, e.StatusCode)
+}
+
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
@@ -0,0 +1,129 @@
+package goat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HeaderProvider computes headers when the request is sent, ex: timestamps, nonces
+// or values taken from the request context, an error aborts the request
+type HeaderProvider func(request *http.Request) (http.Header, error)
+
+// HeaderMergeStrategy how a header set both on the client and on the request is merged
+type HeaderMergeStrategy int
+
+const (
+	// HeaderOverride the request values replace the client ones, the default
+	HeaderOverride HeaderMergeStrategy = iota
+	// HeaderAppend the request values are sent after the client ones
+	HeaderAppend
+	// HeaderKeepConfig the client values win, the request ones are only used
+	// when the client doesn't set the header
+	HeaderKeepConfig
+)
+
+// hopByHopHeaders only make sense for a single connection, the transport
+// manages them so they're never forwarded
+var hopByHopHeaders = []string{
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// mergeHeaders adds the request values to the merged headers following the strategy
+func mergeHeaders(merged http.Header, header string, values []string, strategy HeaderMergeStrategy) {
+	switch strategy {
+	case HeaderAppend:
+		merged[header] = append(merged[header], values...)
+	case HeaderKeepConfig:
+		if len(merged[header]) == 0 {
+			merged[header] = append([]string(nil), values...)
+		}
+	default:
+		merged[header] = append([]string(nil), values...)
+	}
+}
+
+// applyHeaders sets the merged headers on the request, the ones http.Request
+// takes from its own fields are moved there and the hop-by-hop ones dropped
+func applyHeaders(request *http.Request, header http.Header) {
+	// the transport writes the host and the length from the request fields
+	if host := header.Get("Host"); host != "" {
+		request.Host = host
+	}
+	header.Del("Host")
+	header.Del("Content-Length")
+
+	// upgrades, ex: websockets, keep their Upgrade header
+	upgrade := header.Get("Upgrade")
+	for _, hopByHop := range hopByHopHeaders {
+		header.Del(hopByHop)
+	}
+
+	if connection := header.Values("Connection"); len(connection) > 0 {
+		header.Del("Connection")
+		for _, value := range connection {
+			for _, option := range strings.Split(value, ",") {
+				switch option = strings.TrimSpace(option); {
+				case strings.EqualFold(option, "close"):
+					request.Close = true
+				case strings.EqualFold(option, "upgrade") && upgrade != "":
+					header.Add("Connection", "Upgrade")
+					header.Set("Upgrade", upgrade)
+				}
+			}
+		}
+	}
+
+	// cookies go in a single header joined by "; " (RFC 6265)
+	if cookies := header.Values("Cookie"); len(cookies) > 1 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	request.Header = header
+}
+
+// providerError error returned by a header provider, it aborts the request without retries
+type providerError struct {
+	err error
+}
+
+func (e *providerError) Error() string {
+	return "unable to provide headers: " + e.err.Error()
+}
+
+func (e *providerError) Unwrap() error {
+	return e.err
+}
+
+// provideHeaders returns a copy of the attempt with the headers of every provider,
+// merged in the order they were registered following the merge strategies the
+// same way request headers are, so every attempt gets fresh values
+func (c *httpClient) provideHeaders(request *http.Request) (*http.Request, error) {
+	providers := c.config.headerProviders
+	if len(providers) == 0 {
+		return request, nil
+	}
+
+	// the copy shares the body, only the headers change
+	attempt := request.Clone(request.Context())
+	for _, provider := range providers {
+		headers, err := provider(attempt)
+		if err != nil {
+			return nil, &providerError{err: err}
+		}
+
+		for header, values := range headers {
+			if len(values) > 0 {
+				header = http.CanonicalHeaderKey(header)
+				mergeHeaders(attempt.Header, header, values, c.config.headerStrategies[header])
+			}
+		}
+	}
+
+	applyHeaders(attempt, attempt.Header)
+	return attempt, nil
+}
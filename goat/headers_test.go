@@ -0,0 +1,209 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeaderMerge(t *testing.T) {
+	configHeaders := http.Header{
+		"Accept":          {"application/json", "application/xml"},
+		"X-Forwarded-For": {"10.0.0.1"},
+		"X-Tenant":        {"config"},
+		"x-lower":         {"config"},
+	}
+
+	client := New().
+		SetHeaders(configHeaders).
+		SetHeaderMergeStrategy("x-forwarded-for", HeaderAppend).
+		SetHeaderMergeStrategy("X-Tenant", HeaderKeepConfig).
+		Create().(*httpClient)
+
+	t.Run("TestKeepsEveryValue", func(t *testing.T) {
+		headers := client.setHeaders(http.Header{})
+		if !reflect.DeepEqual(headers["Accept"], []string{"application/json", "application/xml"}) {
+			t.Errorf("it should keep multi-valued headers, got %v", headers["Accept"])
+		}
+
+		if headers.Get("X-Lower") != "config" {
+			t.Errorf("it should canonicalize the header keys, got %v", headers)
+		}
+	})
+
+	t.Run("TestStrategies", func(t *testing.T) {
+		headers := client.setHeaders(http.Header{
+			"Accept":          {"text/csv"},
+			"X-Forwarded-For": {"10.0.0.2", "10.0.0.3"},
+			"X-Tenant":        {"request"},
+		})
+
+		if !reflect.DeepEqual(headers["Accept"], []string{"text/csv"}) {
+			t.Errorf("it should override by default, got %v", headers["Accept"])
+		}
+
+		if !reflect.DeepEqual(headers["X-Forwarded-For"], []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}) {
+			t.Errorf("it should append the request values, got %v", headers["X-Forwarded-For"])
+		}
+
+		if !reflect.DeepEqual(headers["X-Tenant"], []string{"config"}) {
+			t.Errorf("it should keep the config values, got %v", headers["X-Tenant"])
+		}
+	})
+
+	t.Run("TestKeepConfigWithoutConfigValue", func(t *testing.T) {
+		headers := New().
+			SetHeaderMergeStrategy("X-Tenant", HeaderKeepConfig).
+			Create().(*httpClient).
+			setHeaders(http.Header{"X-Tenant": {"request"}})
+
+		if headers.Get("X-Tenant") != "request" {
+			t.Errorf("it should use the request value, got %v", headers["X-Tenant"])
+		}
+	})
+}
+
+func TestApplyHeaders(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer server.Close()
+
+	client := New().Create()
+	_, err := client.Get(server.URL, http.Header{
+		"Host":              {"api.goat.com"},
+		"Content-Length":    {"999"},
+		"Keep-Alive":        {"timeout=5"},
+		"Transfer-Encoding": {"chunked"},
+		"Connection":        {"close"},
+		"Cookie":            {"a=1", "b=2"},
+		"X-Forwarded-For":   {"10.0.0.1", "10.0.0.2"},
+	})
+	if err != nil {
+		t.Fatalf("it should return nil error, got %v", err)
+	}
+
+	request := <-received
+	if request.Host != "api.goat.com" {
+		t.Errorf("it should send the Host header as the request host, got %s", request.Host)
+	}
+
+	if request.ContentLength != 0 || request.Header.Get("Keep-Alive") != "" || len(request.TransferEncoding) != 0 {
+		t.Errorf("it should drop the hop-by-hop and length headers, got %v", request.Header)
+	}
+
+	if !request.Close {
+		t.Errorf("it should close the connection")
+	}
+
+	if request.Header.Get("Cookie") != "a=1; b=2" {
+		t.Errorf("it should join the cookies, got %v", request.Header["Cookie"])
+	}
+
+	if forwarded := strings.Join(request.Header.Values("X-Forwarded-For"), ","); forwarded != "10.0.0.1,10.0.0.2" {
+		t.Errorf("it should send every value, got %s", forwarded)
+	}
+}
+
+type tenantKey struct{}
+
+func TestHeaderProviders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+	}))
+	defer server.Close()
+
+	var calls int
+	client := New().
+		SetHeaders(http.Header{"X-Api-Key": {"static"}}).
+		AddHeaderProviders(
+			func(request *http.Request) (http.Header, error) {
+				calls++
+				return http.Header{"X-Api-Key": {"rotated"}, "x-nonce": {strconv.Itoa(calls)}}, nil
+			},
+			func(request *http.Request) (http.Header, error) {
+				tenant, _ := request.Context().Value(tenantKey{}).(string)
+				if tenant == "" {
+					return nil, errors.New("missing tenant")
+				}
+				return http.Header{"X-Tenant": {tenant}}, nil
+			},
+		).
+		Create()
+
+	t.Run("TestProvidedHeaders", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), tenantKey{}, "goat")
+		if _, err := client.GetWithContext(ctx, server.URL, nil); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		headers := <-received
+		if headers.Get("X-Api-Key") != "rotated" || headers.Get("X-Nonce") != "1" || headers.Get("X-Tenant") != "goat" {
+			t.Errorf("it should send the provided headers, got %v", headers)
+		}
+	})
+
+	t.Run("TestProviderError", func(t *testing.T) {
+		_, err := client.Get(server.URL, nil)
+		if err == nil || !strings.Contains(err.Error(), "missing tenant") {
+			t.Errorf("it should abort the request, got %v", err)
+		}
+
+		if len(received) != 0 {
+			t.Errorf("it should not send the request")
+		}
+	})
+
+	t.Run("TestFreshValuesPerAttempt", func(t *testing.T) {
+		nonces := make(chan string, 2)
+		var attempts int32
+		retryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonces <- r.Header.Get("X-Nonce")
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer retryServer.Close()
+
+		var calls int32
+		retryClient := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			SetHeaderMergeStrategy("X-Nonce", HeaderAppend).
+			AddHeaderProviders(func(request *http.Request) (http.Header, error) {
+				return http.Header{"X-Nonce": {strconv.Itoa(int(atomic.AddInt32(&calls, 1)))}}, nil
+			}).
+			Create()
+
+		if resp, err := retryClient.Get(retryServer.URL); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("it should succeed on the second attempt, got %v", err)
+		}
+
+		if first, second := <-nonces, <-nonces; first != "1" || second != "2" {
+			t.Errorf("it should provide new values on every attempt, got %s and %s", first, second)
+		}
+	})
+
+	t.Run("TestProviderErrorIsNotRetried", func(t *testing.T) {
+		var calls int32
+		retryClient := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}).
+			AddHeaderProviders(func(request *http.Request) (http.Header, error) {
+				atomic.AddInt32(&calls, 1)
+				return nil, errors.New("no key")
+			}).
+			Create()
+
+		if _, err := retryClient.Get(server.URL); err == nil || calls != 1 {
+			t.Errorf("it should abort on the first error, got %v after %d calls", err, calls)
+		}
+	})
+}
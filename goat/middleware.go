@@ -0,0 +1,27 @@
+package goat
+
+import (
+	"net/http"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+// Handler performs an outgoing request and returns its buffered response
+type Handler func(request *http.Request) (*core.Response, error)
+
+// Middleware intercepts every request sent by the client, it can modify the request,
+// short-circuit it by not calling next, or post-process the response returned by next
+type Middleware func(request *http.Request, next Handler) (*core.Response, error)
+
+// chain wraps the handler with the configured middlewares, the first
+// middleware registered is the outermost one
+func (c *httpClient) chain(handler Handler) Handler {
+	middlewares := c.config.middlewares
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, next := middlewares[i], handler
+		handler = func(request *http.Request) (*core.Response, error) {
+			return middleware(request, next)
+		}
+	}
+	return handler
+}
@@ -0,0 +1,87 @@
+package goat
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Auth", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("TestModifiesRequestAndResponse", func(t *testing.T) {
+		client := New().
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				request.Header.Set("Authorization", "Bearer token")
+				response, err := next(request)
+				if err != nil {
+					return nil, err
+				}
+				response.Headers.Set("X-Post-Processed", "true")
+				return response, nil
+			}).
+			Create()
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if resp.Headers.Get("X-Seen-Auth") != "Bearer token" {
+			t.Errorf("it should send the header added by the middleware")
+		}
+
+		if resp.Headers.Get("X-Post-Processed") != "true" {
+			t.Errorf("it should return the post processed response")
+		}
+	})
+
+	t.Run("TestShortCircuit", func(t *testing.T) {
+		expected := errors.New("short-circuited")
+		client := New().
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				return nil, expected
+			}).
+			Create()
+
+		if _, err := client.Get(server.URL); err != expected {
+			t.Errorf("it should return the middleware error")
+		}
+	})
+
+	t.Run("TestOrder", func(t *testing.T) {
+		var calls []string
+		record := func(name string) Middleware {
+			return func(request *http.Request, next Handler) (*core.Response, error) {
+				calls = append(calls, name+":before")
+				response, err := next(request)
+				calls = append(calls, name+":after")
+				return response, err
+			}
+		}
+
+		client := New().
+			Use(record("first")).
+			Use(record("second")).
+			Create()
+
+		client.Get(server.URL)
+
+		expected := []string{"first:before", "second:before", "second:after", "first:after"}
+		if len(calls) != len(expected) {
+			t.Fatalf("it should call %v, got %v", expected, calls)
+		}
+		for i := range expected {
+			if calls[i] != expected[i] {
+				t.Errorf("it should call %v, got %v", expected, calls)
+			}
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package goat
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	stdmime "mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Multipart multipart/form-data body combining text fields, files and readers,
+// parts are streamed to the transport instead of buffered in memory, ex:
+//
+//	body := goat.NewMultipart().
+//		AddField("name", "avatar").
+//		AddFile("file", "/tmp/avatar.png")
+//	client.Post("https://api.com/upload", body)
+//
+// The Content-Length is set when the size of every part is known, and the body
+// can be replayed on retries unless it has readers that can't seek
+type Multipart struct {
+	boundary string
+	parts    []*multipartPart
+}
+
+type multipartPart struct {
+	field       string
+	filename    string
+	contentType string
+	value       string
+	path        string
+	reader      io.Reader
+	size        int64
+	offset      int64 // initial position of seekable readers
+}
+
+// NewMultipart creates an empty multipart body with a random boundary
+func NewMultipart() *Multipart {
+	return &Multipart{
+		boundary: multipart.NewWriter(ioutil.Discard).Boundary(),
+	}
+}
+
+// AddField adds a text field
+func (m *Multipart) AddField(field string, value string) *Multipart {
+	m.parts = append(m.parts, &multipartPart{field: field, value: value, size: int64(len(value))})
+	return m
+}
+
+// AddFile adds a file read from disk when the request is sent, its content
+// type is guessed from the file extension
+func (m *Multipart) AddFile(field string, path string) *Multipart {
+	contentType := stdmime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = mime.ApplicationTypeOctetStream
+	}
+
+	m.parts = append(m.parts, &multipartPart{
+		field:       field,
+		filename:    filepath.Base(path),
+		contentType: contentType,
+		path:        path,
+		size:        -1,
+	})
+	return m
+}
+
+// AddReader adds a part read from r with its own filename and content type,
+// size is -1 when unknown, seekable readers are rewound to replay the body
+func (m *Multipart) AddReader(field string, filename string, contentType string, r io.Reader, size int64) *Multipart {
+	part := &multipartPart{
+		field:       field,
+		filename:    filename,
+		contentType: contentType,
+		reader:      r,
+		size:        size,
+	}
+	if seeker, ok := r.(io.Seeker); ok {
+		part.offset, _ = seeker.Seek(0, io.SeekCurrent)
+	}
+
+	m.parts = append(m.parts, part)
+	return m
+}
+
+// ContentType returns the content type including the boundary
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+func (m *Multipart) requestBody() (*requestBody, error) {
+	length, err := m.length()
+	if err != nil {
+		return nil, err
+	}
+
+	body := &requestBody{
+		reader:      m.reader(),
+		length:      length,
+		contentType: m.ContentType(),
+	}
+
+	if m.replayable() {
+		body.getBody = func() (io.ReadCloser, error) {
+			if err := m.rewind(); err != nil {
+				return nil, err
+			}
+			return m.reader(), nil
+		}
+	}
+	return body, nil
+}
+
+// length returns the exact size of the encoded body, or -1 when a part size is unknown
+func (m *Multipart) length() (int64, error) {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(m.boundary); err != nil {
+		return 0, err
+	}
+
+	known := true
+	var total int64
+	for _, part := range m.parts {
+		if part.path != "" {
+			info, err := os.Stat(part.path)
+			if err != nil {
+				return 0, err
+			}
+			part.size = info.Size()
+		}
+
+		if part.size < 0 {
+			known = false
+		}
+		total += part.size
+
+		if _, err := writer.CreatePart(part.header()); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	if !known {
+		return -1, nil
+	}
+	return total + counter.n, nil
+}
+
+func (m *Multipart) replayable() bool {
+	for _, part := range m.parts {
+		if part.reader == nil {
+			continue
+		}
+		if _, ok := part.reader.(io.Seeker); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Multipart) rewind() error {
+	for _, part := range m.parts {
+		if seeker, ok := part.reader.(io.Seeker); ok {
+			if _, err := seeker.Seek(part.offset, io.SeekStart); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reader streams the encoded body through a pipe
+func (m *Multipart) reader() io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+	return &lazyPipeReader{
+		PipeReader: pipeReader,
+		start: func() {
+			go func() {
+				pipeWriter.CloseWithError(m.writeTo(pipeWriter))
+			}()
+		},
+	}
+}
+
+func (m *Multipart) writeTo(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, part := range m.parts {
+		partWriter, err := writer.CreatePart(part.header())
+		if err != nil {
+			return err
+		}
+		if err := part.writeTo(partWriter); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func (p *multipartPart) header() textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(p.field))
+	if p.filename != "" {
+		disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(p.filename))
+	}
+	header.Set("Content-Disposition", disposition)
+
+	if p.contentType != "" {
+		header.Set("Content-Type", p.contentType)
+	}
+	return header
+}
+
+func (p *multipartPart) writeTo(w io.Writer) error {
+	switch {
+	case p.path != "":
+		file, err := os.Open(p.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(w, file)
+		return err
+	case p.reader != nil:
+		_, err := io.Copy(w, p.reader)
+		return err
+	default:
+		_, err := io.WriteString(w, p.value)
+		return err
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,173 @@
+package goat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type multipartResult struct {
+	contentLength int64
+	fields        map[string]string
+	files         map[string]string
+	contentTypes  map[string]string
+}
+
+func newMultipartServer(results chan<- multipartResult) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := multipartResult{
+			contentLength: r.ContentLength,
+			fields:        map[string]string{},
+			files:         map[string]string{},
+			contentTypes:  map[string]string{},
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			results <- result
+			return
+		}
+
+		for field, values := range r.MultipartForm.Value {
+			result.fields[field] = values[0]
+		}
+		for field, headers := range r.MultipartForm.File {
+			file, _ := headers[0].Open()
+			content, _ := ioutil.ReadAll(file)
+			file.Close()
+			result.files[field] = headers[0].Filename + ":" + string(content)
+			result.contentTypes[field] = headers[0].Header.Get("Content-Type")
+		}
+		results <- result
+	}))
+}
+
+func TestMultipart(t *testing.T) {
+	results := make(chan multipartResult, 10)
+	server := newMultipartServer(results)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "goat-multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "notes.txt")
+	ioutil.WriteFile(path, []byte("file content"), 0644)
+
+	client := New().Create()
+
+	t.Run("TestFieldsFilesAndReaders", func(t *testing.T) {
+		body := NewMultipart().
+			AddField("name", "goat").
+			AddFile("document", path).
+			AddReader("avatar", "avatar.png", "image/png", bytes.NewReader([]byte("png")), 3)
+
+		resp, err := client.Post(server.URL, body)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		result := <-results
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("it should send a valid multipart body")
+		}
+
+		if result.fields["name"] != "goat" {
+			t.Errorf("it should send the text fields, got %v", result.fields)
+		}
+
+		if result.files["document"] != "notes.txt:file content" || result.files["avatar"] != "avatar.png:png" {
+			t.Errorf("it should send the files, got %v", result.files)
+		}
+
+		if !strings.HasPrefix(result.contentTypes["document"], "text/plain") || result.contentTypes["avatar"] != "image/png" {
+			t.Errorf("it should send the parts content types, got %v", result.contentTypes)
+		}
+
+		if result.contentLength <= 0 {
+			t.Errorf("it should compute the content length when all sizes are known")
+		}
+	})
+
+	t.Run("TestUnknownSizeStreams", func(t *testing.T) {
+		body := NewMultipart().
+			AddReader("stream", "stream.txt", "text/plain", strings.NewReader("streamed"), -1)
+
+		if _, err := client.Put(server.URL, body); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		result := <-results
+		if result.files["stream"] != "stream.txt:streamed" {
+			t.Errorf("it should stream the reader, got %v", result.files)
+		}
+
+		if result.contentLength != -1 {
+			t.Errorf("it should not set the content length, got %d", result.contentLength)
+		}
+	})
+
+	t.Run("TestMissingFile", func(t *testing.T) {
+		body := NewMultipart().AddFile("document", filepath.Join(dir, "missing.txt"))
+		if _, err := client.Post(server.URL, body); err == nil {
+			t.Errorf("it should fail before sending a missing file")
+		}
+	})
+
+	t.Run("TestReplayOnRetry", func(t *testing.T) {
+		var attempts int32
+		retryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseMultipartForm(1 << 20)
+			if r.MultipartForm == nil || r.MultipartForm.Value["name"][0] != "goat" {
+				t.Errorf("it should replay the whole body")
+			}
+
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer retryServer.Close()
+
+		retryClient := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Create()
+
+		body := NewMultipart().
+			AddField("name", "goat").
+			AddFile("document", path)
+
+		resp, err := retryClient.Put(retryServer.URL, body)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Errorf("it should succeed on the second attempt, got %v", err)
+		}
+
+		if attempts != 2 {
+			t.Errorf("it should perform 2 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("TestContentLengthMatchesBody", func(t *testing.T) {
+		body := NewMultipart().
+			AddField("name", "goat").
+			AddFile("document", path)
+
+		length, err := body.length()
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		encoded, _ := ioutil.ReadAll(body.reader())
+		if int64(len(encoded)) != length {
+			t.Errorf("it should compute the exact length, got %d want %d", length, len(encoded))
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package goat
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+// AuthStyle how the client credentials are sent to the token endpoint
+type AuthStyle int
+
+const (
+	// AuthStyleBasic sends them in the Authorization header, client_secret_basic
+	AuthStyleBasic AuthStyle = iota
+	// AuthStylePost sends them in the form body, client_secret_post
+	AuthStylePost
+)
+
+// ClientCredentials TokenSource fetching tokens with the OAuth2 client credentials
+// grant (RFC 6749 section 4.4), ex:
+//
+//	client := goat.New().
+//		SetTokenSource(&goat.ClientCredentials{
+//			TokenURL:     "https://auth.com/oauth/token",
+//			ClientID:     "id",
+//			ClientSecret: "secret",
+//			Scopes:       []string{"orders:read"},
+//		}).
+//		Create()
+//
+// The client caches the tokens, so the endpoint is only called to get a new one
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Audience sent as the audience param, required by some providers like Auth0
+	Audience string
+	// AuthStyle client_secret_basic by default
+	AuthStyle AuthStyle
+	// Client used to call the token endpoint, a default one when nil, it shouldn't
+	// use this token source itself
+	Client Client
+
+	clientOnce sync.Once
+	client     Client
+}
+
+// TokenError error response of the token endpoint (RFC 6749 section 5.2)
+type TokenError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	URI         string `json:"error_uri"`
+}
+
+func (e *TokenError) Error() string {
+	message := fmt.Sprintf("oauth2 token request failed with status %d", e.StatusCode)
+	if e.Code != "" {
+		message += ": " + e.Code
+	}
+	if e.Description != "" {
+		message += ", " + e.Description
+	}
+	return message
+}
+
+// tokenResponse successful response of the token endpoint (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn seconds, some providers send it as a string
+	ExpiresIn interface{} `json:"expires_in"`
+}
+
+// Token requests a new token from the token endpoint
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if c.Audience != "" {
+		form.Set("audience", c.Audience)
+	}
+
+	request := c.getClient().R().
+		SetContext(ctx).
+		SetHeader(mime.HeaderContentType, mime.ApplicationTypeForm).
+		SetHeader(mime.HeaderAccept, mime.ApplicationTypeJSON)
+
+	switch c.AuthStyle {
+	case AuthStylePost:
+		form.Set("client_id", c.ClientID)
+		form.Set("client_secret", c.ClientSecret)
+	default:
+		// the credentials are form encoded before the base64 encoding (RFC 6749 section 2.3.1)
+		credentials := url.QueryEscape(c.ClientID) + ":" + url.QueryEscape(c.ClientSecret)
+		request.SetHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+
+	response, err := request.SetBody(form).Post(c.TokenURL)
+	if err != nil {
+		var httpErr *core.HTTPError
+		if !errors.As(err, &httpErr) {
+			return nil, err
+		}
+	}
+
+	if !response.IsSuccess() {
+		tokenErr := &TokenError{StatusCode: response.StatusCode}
+		response.UnmarshalJson(tokenErr)
+		return nil, tokenErr
+	}
+
+	var body tokenResponse
+	if err := response.UnmarshalJson(&body); err != nil {
+		return nil, fmt.Errorf("unable to decode oauth2 token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("oauth2 token response without access_token")
+	}
+
+	token := &Token{AccessToken: body.AccessToken, TokenType: body.TokenType}
+	if expiresIn := parseExpiresIn(body.ExpiresIn); expiresIn > 0 {
+		token.Expiry = time.Now().Add(expiresIn)
+	}
+
+	// the scheme is case insensitive, but some servers only accept "Bearer"
+	if strings.EqualFold(token.TokenType, "bearer") {
+		token.TokenType = "Bearer"
+	}
+	return token, nil
+}
+
+func (c *ClientCredentials) getClient() Client {
+	if c.Client != nil {
+		return c.Client
+	}
+
+	c.clientOnce.Do(func() {
+		c.client = New().Create()
+	})
+	return c.client
+}
+
+// parseExpiresIn accepts the expires_in seconds as a number or a string
+func parseExpiresIn(value interface{}) time.Duration {
+	var seconds float64
+	switch v := value.(type) {
+	case float64:
+		seconds = v
+	case string:
+		fmt.Sscan(v, &seconds)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,162 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+	"github.com/andresmijares/goat-rest/goat_mock"
+)
+
+func TestClientCredentials(t *testing.T) {
+	tokenURL := "https://auth.goat.com/oauth/token"
+	apiURL := "https://api.goat.com/orders"
+	jsonHeaders := http.Header{"Content-Type": {"application/json"}}
+
+	goat_mock.MockupServer.Start()
+	goat_mock.MockupServer.Flush()
+	defer goat_mock.MockupServer.Stop()
+
+	goat_mock.MockupServer.Add(goat_mock.Mock{
+		Method:             http.MethodPost,
+		URL:                tokenURL,
+		RequestBody:        "audience=orders-api&grant_type=client_credentials&scope=orders%3Aread+orders%3Awrite",
+		ResponseStatusCode: http.StatusOK,
+		ResponseBody:       `{"access_token":"basic-token","token_type":"bearer","expires_in":3600}`,
+		ResponseHeaders:    jsonHeaders,
+	})
+	goat_mock.MockupServer.Add(goat_mock.Mock{
+		Method:             http.MethodPost,
+		URL:                tokenURL,
+		RequestBody:        "client_id=goat&client_secret=s%26cret&grant_type=client_credentials",
+		ResponseStatusCode: http.StatusOK,
+		ResponseBody:       `{"access_token":"post-token","token_type":"Bearer","expires_in":"60"}`,
+		ResponseHeaders:    jsonHeaders,
+	})
+	goat_mock.MockupServer.Add(goat_mock.Mock{
+		Method:             http.MethodPost,
+		URL:                tokenURL,
+		RequestBody:        "client_id=goat&client_secret=wrong&grant_type=client_credentials",
+		ResponseStatusCode: http.StatusUnauthorized,
+		ResponseBody:       `{"error":"invalid_client","error_description":"bad credentials","statusCode":200}`,
+		ResponseHeaders:    jsonHeaders,
+	})
+	goat_mock.MockupServer.Add(goat_mock.Mock{
+		Method:             http.MethodGet,
+		URL:                apiURL,
+		ResponseStatusCode: http.StatusOK,
+		ResponseBody:       `[]`,
+	})
+
+	// tokenClient records the requests sent to the token endpoint
+	tokenRequests := make(chan *http.Request, 10)
+	tokenClient := New().
+		Use(func(request *http.Request, next Handler) (*core.Response, error) {
+			tokenRequests <- request
+			return next(request)
+		}).
+		Create()
+
+	t.Run("TestClientSecretBasic", func(t *testing.T) {
+		source := &ClientCredentials{
+			TokenURL:     tokenURL,
+			ClientID:     "goat",
+			ClientSecret: "s&cret",
+			Scopes:       []string{"orders:read", "orders:write"},
+			Audience:     "orders-api",
+			Client:       tokenClient,
+		}
+
+		token, err := source.Token(context.Background())
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if token.AccessToken != "basic-token" || token.TokenType != "Bearer" {
+			t.Errorf("it should decode the token, got %+v", token)
+		}
+
+		if expiry := time.Until(token.Expiry); expiry < 59*time.Minute || expiry > time.Hour {
+			t.Errorf("it should set the expiry from expires_in, got %s", expiry)
+		}
+
+		request := <-tokenRequests
+		if id, secret, ok := request.BasicAuth(); !ok || id != "goat" || secret != "s%26cret" {
+			t.Errorf("it should send the form encoded credentials as basic auth, got %s:%s", id, secret)
+		}
+
+		if request.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("it should post a form, got %s", request.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("TestClientSecretPost", func(t *testing.T) {
+		source := &ClientCredentials{
+			TokenURL:     tokenURL,
+			ClientID:     "goat",
+			ClientSecret: "s&cret",
+			AuthStyle:    AuthStylePost,
+			Client:       tokenClient,
+		}
+
+		token, err := source.Token(context.Background())
+		if err != nil || token.AccessToken != "post-token" {
+			t.Fatalf("it should send the credentials in the body, got %v", err)
+		}
+
+		if expiry := time.Until(token.Expiry); expiry <= 0 || expiry > time.Minute {
+			t.Errorf("it should accept expires_in as a string, got %s", expiry)
+		}
+
+		if request := <-tokenRequests; request.Header.Get("Authorization") != "" {
+			t.Errorf("it should not send basic auth")
+		}
+	})
+
+	t.Run("TestTokenError", func(t *testing.T) {
+		source := &ClientCredentials{
+			TokenURL:     tokenURL,
+			ClientID:     "goat",
+			ClientSecret: "wrong",
+			AuthStyle:    AuthStylePost,
+		}
+
+		_, err := source.Token(context.Background())
+
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			t.Fatalf("it should return a *TokenError, got %v", err)
+		}
+
+		if tokenErr.StatusCode != http.StatusUnauthorized || tokenErr.Code != "invalid_client" || tokenErr.Description != "bad credentials" {
+			t.Errorf("it should decode the error response, got %+v", tokenErr)
+		}
+	})
+
+	t.Run("TestAuthorizesClient", func(t *testing.T) {
+		client := New().
+			SetTokenSource(&ClientCredentials{
+				TokenURL:     tokenURL,
+				ClientID:     "goat",
+				ClientSecret: "s&cret",
+				AuthStyle:    AuthStylePost,
+				Client:       tokenClient,
+			}).
+			Create()
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(apiURL)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Fatalf("it should authorize the request, got %v", err)
+			}
+		}
+
+		if len(tokenRequests) != 1 {
+			t.Errorf("it should fetch the token once, got %d", len(tokenRequests))
+		}
+		<-tokenRequests
+	})
+}
@@ -0,0 +1,158 @@
+package goat
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit configures a client side token bucket, requests wait for a free
+// token unless NonBlocking is set
+type RateLimit struct {
+	// RequestsPerSecond rate at which tokens are refilled
+	RequestsPerSecond float64
+	// Burst max tokens available at once, defaults to RequestsPerSecond rounded up
+	Burst int
+	// PerHost keeps an independent bucket for every host instead of a global one
+	PerHost bool
+	// NonBlocking fails with a RateLimitError instead of waiting for a token
+	NonBlocking bool
+}
+
+// RateLimitError is returned in non blocking mode when there are no tokens left,
+// errors.Is(err, ErrRateLimited) matches it
+type RateLimitError struct {
+	Host string
+	// RetryAfter how long until a token is available
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error() + " for host " + e.Host + ", retry after " + e.RetryAfter.String()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// rateLimiters keeps the global or per host buckets, its zero value is ready to use
+type rateLimiters struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (l *rateLimiters) get(key string, limit *RateLimit) *tokenBucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = newTokenBucket(limit.RequestsPerSecond, limit.getBurst())
+		l.buckets[key] = bucket
+	}
+	return bucket
+}
+
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it, when
+// wait is false the token is only taken if it's available right away
+func (b *tokenBucket) reserve(wait bool) (time.Duration, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if !wait {
+		return delay, false
+	}
+
+	// tokens go negative so the following callers queue behind this one
+	b.tokens--
+	return delay, true
+}
+
+// cancel returns a reserved token that won't be used
+func (b *tokenBucket) cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// waitRateLimit blocks until the request is allowed by the configured rate limit
+func (c *httpClient) waitRateLimit(request *http.Request) error {
+	limit := c.config.rateLimit
+	if limit == nil || limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	key := ""
+	if limit.PerHost {
+		key = request.URL.Host
+	}
+	bucket := c.limiters.get(key, limit)
+
+	delay, ok := bucket.reserve(!limit.NonBlocking)
+	if !ok {
+		return &RateLimitError{Host: request.URL.Host, RetryAfter: delay}
+	}
+
+	if delay <= 0 {
+		return nil
+	}
+
+	return sleepContext(request.Context(), delay, bucket.cancel)
+}
+
+// sleepContext waits for the given delay or until the context is done, calling
+// onCancel, when set, in the latter case
+func sleepContext(ctx context.Context, delay time.Duration, onCancel func()) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		if onCancel != nil {
+			onCancel()
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *RateLimit) getBurst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return int(math.Max(1, math.Ceil(l.RequestsPerSecond)))
+}
@@ -0,0 +1,126 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("TestBlocksUntilTokenIsFree", func(t *testing.T) {
+		client := New().
+			SetRateLimit(RateLimit{RequestsPerSecond: 20, Burst: 1}).
+			Create()
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if _, err := client.Get(server.URL); err != nil {
+				t.Errorf("it should return nil error, got %v", err)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("it should wait for tokens, took %v", elapsed)
+		}
+	})
+
+	t.Run("TestNonBlocking", func(t *testing.T) {
+		client := New().
+			SetRateLimit(RateLimit{RequestsPerSecond: 1, Burst: 1, NonBlocking: true}).
+			Create()
+
+		if _, err := client.Get(server.URL); err != nil {
+			t.Errorf("it should allow the burst, got %v", err)
+		}
+
+		_, err := client.Get(server.URL)
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("it should return a rate limited error, got %v", err)
+		}
+
+		var limitErr *RateLimitError
+		if !errors.As(err, &limitErr) || limitErr.RetryAfter <= 0 {
+			t.Errorf("it should report when a token is available")
+		}
+	})
+
+	t.Run("TestNonBlockingIsNotRetried", func(t *testing.T) {
+		client := New().
+			SetRateLimit(RateLimit{RequestsPerSecond: 1, Burst: 1, NonBlocking: true}).
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}).
+			Create()
+
+		client.Get(server.URL)
+
+		start := time.Now()
+		if _, err := client.Get(server.URL); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("it should fail fast, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("it should not wait for a retry, took %v", elapsed)
+		}
+	})
+
+	t.Run("TestContextEndsWait", func(t *testing.T) {
+		client := New().
+			SetRateLimit(RateLimit{RequestsPerSecond: 0.1, Burst: 1}).
+			Create()
+
+		client.Get(server.URL)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.GetWithContext(ctx, server.URL)
+		if !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should stop waiting once the context ends, got %v", err)
+		}
+	})
+
+	t.Run("TestPerHost", func(t *testing.T) {
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer other.Close()
+
+		client := New().
+			SetRateLimit(RateLimit{RequestsPerSecond: 1, Burst: 1, PerHost: true, NonBlocking: true}).
+			Create()
+
+		if _, err := client.Get(server.URL); err != nil {
+			t.Errorf("it should return nil error, got %v", err)
+		}
+
+		if _, err := client.Get(other.URL); err != nil {
+			t.Errorf("it should keep a bucket per host, got %v", err)
+		}
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(10, 2)
+
+	for i := 0; i < 2; i++ {
+		if delay, ok := bucket.reserve(false); !ok || delay != 0 {
+			t.Errorf("it should allow the burst right away")
+		}
+	}
+
+	if _, ok := bucket.reserve(false); ok {
+		t.Errorf("it should not take a token when empty")
+	}
+
+	delay, ok := bucket.reserve(true)
+	if !ok || delay <= 0 || delay > 100*time.Millisecond {
+		t.Errorf("it should reserve a future token, got %v", delay)
+	}
+}
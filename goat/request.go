@@ -0,0 +1,436 @@
+package goat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+// pathParamPattern matches the {param} placeholders of a path template
+var pathParamPattern = regexp.MustCompile(`\{[^{}/?#]+\}`)
+
+// Request fluent builder for a single request, created with Client.R() and
+// finished by any of the http methods, ex:
+//
+//	client.R().SetPathParam("id", "42").SetResult(&user).Get("https://api.com/users/{id}")
+type Request struct {
+	client *httpClient
+
+	ctx         context.Context
+	headers     http.Header
+	queryParams url.Values
+	pathParams  map[string]string
+	body        interface{}
+	timeout     time.Duration
+	result      interface{}
+	errorResult interface{}
+
+	maxResponseBytes int64
+	decodeOptions    mime.DecodeOptions
+	compression      *RequestCompression
+	compressionSet   bool
+
+	// err keeps the first error found while building the request, it's
+	// returned once the request is executed
+	err error
+}
+
+// R creates a new request builder
+func (c *httpClient) R() *Request {
+	return &Request{
+		client:      c,
+		ctx:         context.Background(),
+		headers:     make(http.Header),
+		queryParams: make(url.Values),
+		pathParams:  make(map[string]string),
+	}
+}
+
+// SetContext attaches the context to the request, for cancellation and deadlines
+func (r *Request) SetContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// SetHeader sets a single header, replacing any previous value
+func (r *Request) SetHeader(header string, value string) *Request {
+	r.headers.Set(header, value)
+	return r
+}
+
+// SetHeaders adds all the given headers to the request
+func (r *Request) SetHeaders(headers http.Header) *Request {
+	for header, values := range headers {
+		r.headers[http.CanonicalHeaderKey(header)] = append([]string(nil), values...)
+	}
+	return r
+}
+
+// SetQueryParam sets a single query parameter, replacing any previous value
+func (r *Request) SetQueryParam(param string, value string) *Request {
+	r.queryParams.Set(param, value)
+	return r
+}
+
+// SetQueryParams sets all the given query parameters, params can be url.Values,
+// map[string]string or a struct tagged with `url:"name,omitempty"`, see mime.EncodeValues
+// for the encoding rules
+func (r *Request) SetQueryParams(params interface{}) *Request {
+	values, err := mime.EncodeValues(params, "url")
+	if err != nil {
+		r.setError(err)
+		return r
+	}
+
+	for param, list := range values {
+		r.queryParams[param] = list
+	}
+	return r
+}
+
+// SetPathParam sets the value used for the {param} placeholder of the url
+func (r *Request) SetPathParam(param string, value string) *Request {
+	r.pathParams[param] = value
+	return r
+}
+
+// SetPathParams sets the values used for the {param} placeholders of the url
+func (r *Request) SetPathParams(params map[string]string) *Request {
+	for param, value := range params {
+		r.pathParams[param] = value
+	}
+	return r
+}
+
+// SetBody sets the request body, it's encoded following the request content type
+func (r *Request) SetBody(body interface{}) *Request {
+	r.body = body
+	return r
+}
+
+// SetTimeout limits how long the whole request can take, including reading the response
+func (r *Request) SetTimeout(timeout time.Duration) *Request {
+	r.timeout = timeout
+	return r
+}
+
+// SetResult sets the target a successful (2xx) response body is decoded into
+func (r *Request) SetResult(target interface{}) *Request {
+	r.result = target
+	return r
+}
+
+// SetError sets the target a failed (non 2xx) response body is decoded into,
+// the same as with the error type of the client a core.HTTPError is returned
+// for those responses, with the target attached as Decoded
+func (r *Request) SetError(target interface{}) *Request {
+	r.errorResult = target
+	return r
+}
+
+// SetMaxResponseBytes overrides the max response bytes of the client for this
+// request, zero keeps the client limit and a negative value removes it
+func (r *Request) SetMaxResponseBytes(limit int64) *Request {
+	r.maxResponseBytes = limit
+	return r
+}
+
+// SetCompression compresses the body with the given settings, overriding the client ones
+func (r *Request) SetCompression(settings RequestCompression) *Request {
+	r.compression = &settings
+	r.compressionSet = true
+	return r
+}
+
+// DisableCompression sends the body uncompressed even when the client compresses bodies
+func (r *Request) DisableCompression() *Request {
+	r.compression = nil
+	r.compressionSet = true
+	return r
+}
+
+// SetDecodeOptions sets the strict mode options used to decode the result and error targets
+func (r *Request) SetDecodeOptions(options mime.DecodeOptions) *Request {
+	r.decodeOptions = options
+	return r
+}
+
+func (r *Request) Get(url string) (*core.Response, error) {
+	return r.Execute(http.MethodGet, url)
+}
+
+func (r *Request) Post(url string) (*core.Response, error) {
+	return r.Execute(http.MethodPost, url)
+}
+
+func (r *Request) Put(url string) (*core.Response, error) {
+	return r.Execute(http.MethodPut, url)
+}
+
+func (r *Request) Patch(url string) (*core.Response, error) {
+	return r.Execute(http.MethodPatch, url)
+}
+
+func (r *Request) Delete(url string) (*core.Response, error) {
+	return r.Execute(http.MethodDelete, url)
+}
+
+func (r *Request) Options(url string) (*core.Response, error) {
+	return r.Execute(http.MethodOptions, url)
+}
+
+// Execute performs the request with the given method, when a result or error
+// target is set the response body is decoded into it and any decoding
+// error is returned along with the response, the same goes for the
+// core.HTTPError returned for non 2xx responses when enabled, when an error
+// target is set or when the response is an application/problem+json one
+func (r *Request) Execute(method string, url string) (*core.Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	ctx := r.ctx
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	requestURL, err := r.buildURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.execute(ctx, method, requestURL, r.headers, r.body, r.options())
+	if err != nil {
+		return nil, err
+	}
+
+	if response.IsSuccess() {
+		if r.result != nil && len(response.Body) > 0 {
+			if err := response.UnmarshalWithOptions(r.result, r.decodeOptions); err != nil {
+				return response, err
+			}
+		}
+		return response, nil
+	}
+
+	// the request error target wins over the error type of the client
+	errorTarget := r.errorResult
+	if errorTarget == nil {
+		errorTarget = r.client.config.newErrorBody()
+	}
+
+	var decoded interface{}
+	var decodeErr error
+	if errorTarget != nil && len(response.Body) > 0 {
+		if decodeErr = response.UnmarshalWithOptions(errorTarget, r.decodeOptions); decodeErr == nil {
+			decoded = errorTarget
+		}
+	}
+
+	// problem details are always surfaced, the content type says it's an error
+	var problem *core.ProblemDetails
+	if mime.MediaType(response.Headers.Get(mime.HeaderContentType)) == mime.ApplicationTypeProblemJSON {
+		problem = &core.ProblemDetails{}
+		if err := response.UnmarshalJson(problem); err != nil {
+			problem = nil
+		}
+	}
+
+	if problem == nil && r.errorResult == nil && !r.client.config.returnsHTTPErrors() {
+		return response, decodeErr
+	}
+
+	// an undecodable error body shouldn't hide the failed response
+	httpErr := core.NewHTTPError(method, requestURL, response)
+	httpErr.Decoded = decoded
+	httpErr.Problem = problem
+	return response, httpErr
+}
+
+// Stream performs the request with the given method without buffering the
+// response, the status and headers are available right away while the body is
+// read from the connection, ex:
+//
+//	resp, err := client.R().Stream(http.MethodGet, "https://api.com/export")
+//	if err != nil {
+//		return err
+//	}
+//	defer resp.Body.Close()
+//	io.Copy(file, resp.Body)
+//
+// The caller must close the Body. Result and error targets aren't decoded and
+// no core.HTTPError is returned, check the status code instead. Only the
+// connection and response timeouts of the client apply, the time spent reading
+// the body is limited by the context or SetTimeout, the max response bytes don't apply
+func (r *Request) Stream(method string, url string) (*core.StreamResponse, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// the context lives until the body is closed
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if r.timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.ctx, r.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(r.ctx)
+	}
+
+	requestURL, err := r.buildURL(url)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	response, err := r.client.stream(ctx, method, requestURL, r.headers, r.body, r.options())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	response.Body = &streamBody{ReadCloser: response.Body, ctx: ctx, cancel: cancel}
+	return response, nil
+}
+
+// options resolves the settings of the request, falling back to the client ones
+func (r *Request) options() requestOptions {
+	options := requestOptions{
+		compression:      r.client.config.requestCompression,
+		maxResponseBytes: r.client.config.maxResponseBytes,
+	}
+
+	if r.compressionSet {
+		options.compression = r.compression
+	}
+	if r.maxResponseBytes != 0 {
+		options.maxResponseBytes = r.maxResponseBytes
+	}
+	return options
+}
+
+// setError keeps the first error found while building the request
+func (r *Request) setError(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// buildURL fills the path params placeholders, resolves the url against the client
+// base url and merges the query params, the request params replace the ones already
+// in the url while the client defaults only fill the missing ones
+func (r *Request) buildURL(rawURL string) (string, error) {
+	rawURL, err := expandPath(rawURL, r.pathParams)
+	if err != nil {
+		return "", err
+	}
+
+	baseURL := r.client.config.baseURL
+	defaults := r.client.config.queryParams
+	if baseURL == "" && len(r.queryParams) == 0 && len(defaults) == 0 {
+		return rawURL, nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if baseURL != "" {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return "", err
+		}
+		parsed = base.ResolveReference(parsed)
+	}
+
+	parsed.RawQuery = mergeQuery(parsed.RawQuery, r.queryParams, defaults)
+	return parsed.String(), nil
+}
+
+// mergeQuery appends the params to the raw query keeping it as it was written,
+// order and flags without value matter to some apis, only the pairs of the keys
+// replaced by params are removed, defaults are added when the key is missing
+func mergeQuery(rawQuery string, params url.Values, defaults url.Values) string {
+	existing, _ := url.ParseQuery(rawQuery)
+
+	var kept []string
+	if rawQuery != "" {
+		for _, pair := range strings.Split(rawQuery, "&") {
+			key := pair
+			if i := strings.Index(key, "="); i >= 0 {
+				key = key[:i]
+			}
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				if _, ok := params[unescaped]; ok {
+					continue
+				}
+			}
+			kept = append(kept, pair)
+		}
+	}
+
+	added := make(url.Values, len(params))
+	for param, values := range params {
+		added[param] = values
+	}
+	for param, values := range defaults {
+		if _, ok := existing[param]; ok {
+			continue
+		}
+		if _, ok := added[param]; !ok {
+			added[param] = values
+		}
+	}
+
+	if encoded := added.Encode(); encoded != "" {
+		kept = append(kept, encoded)
+	}
+	return strings.Join(kept, "&")
+}
+
+// expandPath replaces every {param} placeholder of the path with its escaped value,
+// so a value can't change the path structure, ex: "a/b" is sent as "a%2Fb" and
+// ".." as "%2E%2E"
+func expandPath(template string, params map[string]string) (string, error) {
+	// the query and fragment are left untouched
+	rest := ""
+	if i := strings.IndexAny(template, "?#"); i >= 0 {
+		template, rest = template[:i], template[i:]
+	}
+
+	var missing error
+	expanded := pathParamPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		param := placeholder[1 : len(placeholder)-1]
+		value, ok := params[param]
+		if !ok {
+			if missing == nil {
+				missing = fmt.Errorf("%w: %s", ErrMissingPathParam, param)
+			}
+			return placeholder
+		}
+		return escapePathParam(value)
+	})
+
+	if missing != nil {
+		return "", missing
+	}
+	return expanded + rest, nil
+}
+
+// escapePathParam escapes the value as a path segment, PathEscape leaves the
+// dot segments as they are and resolving the url would remove them
+func escapePathParam(value string) string {
+	if value == "." || value == ".." {
+		return strings.Repeat("%2E", len(value))
+	}
+	return url.PathEscape(value)
+}
@@ -0,0 +1,371 @@
+package goat
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+type echoResponse struct {
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Query  map[string][]string `json:"query"`
+	Raw    string              `json:"raw"`
+	Header string              `json:"header"`
+	Body   string              `json:"body"`
+}
+
+type errorResponse struct {
+	Message string `json:"message" xml:"message"`
+}
+
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Message: "bad request"})
+			return
+		}
+
+		if r.URL.Path == "/fail.xml" {
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`<error><message>conflict</message></error>`))
+			return
+		}
+
+		if r.URL.Path == "/problem" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"Out of credit","status":403,"detail":"Balance is 30","balance":30}`))
+			return
+		}
+
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(echoResponse{
+			Method: r.Method,
+			Path:   r.URL.EscapedPath(),
+			Query:  r.URL.Query(),
+			Raw:    r.URL.RawQuery,
+			Header: r.Header.Get("X-Custom"),
+			Body:   string(body),
+		})
+	}))
+}
+
+func TestRequestBuilder(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	client := New().Create()
+
+	t.Run("TestParamsHeadersAndResult", func(t *testing.T) {
+		var result echoResponse
+		resp, err := client.R().
+			SetPathParam("id", "a/b").
+			SetQueryParam("page", "2").
+			SetQueryParams(map[string]string{"sort": "asc"}).
+			SetHeader("X-Custom", "custom").
+			SetBody(map[string]string{"foo": "bar"}).
+			SetResult(&result).
+			Post(server.URL + "/users/{id}?existing=true")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("it should return status code 200")
+		}
+
+		if result.Method != http.MethodPost {
+			t.Errorf("it should use the builder method")
+		}
+
+		if result.Path != "/users/a%2Fb" {
+			t.Errorf("it should fill and escape the path params, got %s", result.Path)
+		}
+
+		if result.Query["page"][0] != "2" || result.Query["sort"][0] != "asc" || result.Query["existing"][0] != "true" {
+			t.Errorf("it should send the query params, got %v", result.Query)
+		}
+
+		if result.Header != "custom" {
+			t.Errorf("it should send the headers")
+		}
+
+		if result.Body != `{"foo":"bar"}` {
+			t.Errorf("it should send the body, got %s", result.Body)
+		}
+	})
+
+	t.Run("TestErrorResult", func(t *testing.T) {
+		var result echoResponse
+		var errResult errorResponse
+		resp, err := client.R().
+			SetResult(&result).
+			SetError(&errResult).
+			Get(server.URL + "/fail")
+
+		var httpErr *core.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Decoded != &errResult {
+			t.Fatalf("it should return an HTTPError with the error target, got %v", err)
+		}
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("it should return status code 400")
+		}
+
+		if errResult.Message != "bad request" {
+			t.Errorf("it should decode the error body")
+		}
+
+		if result.Method != "" {
+			t.Errorf("it should not decode the result of a failed response")
+		}
+	})
+
+	t.Run("TestTimeout", func(t *testing.T) {
+		_, err := client.R().
+			SetTimeout(20 * time.Millisecond).
+			Get(server.URL + "/slow")
+		if !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should time out, got %v", err)
+		}
+	})
+
+	t.Run("TestClientMethodsUseBuilder", func(t *testing.T) {
+		headers := make(http.Header)
+		headers.Set("X-Custom", "wrapper")
+
+		resp, err := client.Put(server.URL, []string{"a"}, headers)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		var result echoResponse
+		resp.UnmarshalJson(&result)
+		if result.Method != http.MethodPut || result.Header != "wrapper" || result.Body != `["a"]` {
+			t.Errorf("it should keep the wrapper behavior, got %+v", result)
+		}
+	})
+
+	t.Run("TestQueryParamsStructAndDefaults", func(t *testing.T) {
+		type listParams struct {
+			Page  int      `url:"page,omitempty"`
+			Tags  []string `url:"tag"`
+			Empty string   `url:"empty,omitempty"`
+		}
+
+		client := New().
+			SetQueryParams(url.Values{"api_key": {"secret"}, "page": {"1"}}).
+			Create()
+
+		var result echoResponse
+		_, err := client.R().
+			SetQueryParams(listParams{Page: 3, Tags: []string{"a", "b"}}).
+			SetResult(&result).
+			Get(server.URL + "?existing=true&tag=old")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		expected := url.Values{
+			"api_key":  {"secret"},
+			"page":     {"3"},
+			"tag":      {"a", "b"},
+			"existing": {"true"},
+		}
+		if url.Values(result.Query).Encode() != expected.Encode() {
+			t.Errorf("it should merge the query params, got %v", result.Query)
+		}
+	})
+
+	t.Run("TestKeepsQueryAsWritten", func(t *testing.T) {
+		client := New().SetQueryParams(url.Values{"key": {"k"}}).Create()
+
+		var result echoResponse
+		if _, err := client.R().SetResult(&result).Get(server.URL + "?z=1&a=2&flag"); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if result.Raw != "z=1&a=2&flag&key=k" {
+			t.Errorf("it should append the params to the query, got %s", result.Raw)
+		}
+
+		_, err := client.R().
+			SetQueryParam("z", "9").
+			SetResult(&result).
+			Get(server.URL + "?z=1&a=2&flag")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if result.Raw != "a=2&flag&key=k&z=9" {
+			t.Errorf("it should only rewrite the replaced params, got %s", result.Raw)
+		}
+	})
+
+	t.Run("TestQueryParamsError", func(t *testing.T) {
+		_, err := client.R().SetQueryParams("invalid").Get(server.URL)
+		if err == nil {
+			t.Errorf("it should return the encoding error")
+		}
+	})
+
+	t.Run("TestBaseURL", func(t *testing.T) {
+		client := New().SetBaseURL(server.URL + "/api/v1/").Create()
+
+		var result echoResponse
+		_, err := client.R().
+			SetPathParams(map[string]string{"owner": "goat rest", "repo": "a/b"}).
+			SetResult(&result).
+			Get("users/{owner}/repos/{repo}?raw={literal}")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if result.Path != "/api/v1/users/goat%20rest/repos/a%2Fb" {
+			t.Errorf("it should resolve against the base url, got %s", result.Path)
+		}
+
+		if result.Query["raw"][0] != "{literal}" {
+			t.Errorf("it should leave the query untouched, got %v", result.Query)
+		}
+
+		_, err = client.R().
+			SetPathParam("id", "..").
+			SetResult(&result).
+			Get("users/{id}/repos")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if result.Path != "/api/v1/users/%2E%2E/repos" {
+			t.Errorf("it should escape dot segment params, got %s", result.Path)
+		}
+
+		resp, err := client.Get(server.URL + "/absolute")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		resp.UnmarshalJson(&result)
+		if result.Path != "/absolute" {
+			t.Errorf("it should keep absolute urls, got %s", result.Path)
+		}
+	})
+
+	t.Run("TestMissingPathParam", func(t *testing.T) {
+		_, err := client.R().
+			SetPathParam("id", "1").
+			Get(server.URL + "/users/{id}/repos/{repo}")
+		if !errors.Is(err, ErrMissingPathParam) {
+			t.Errorf("it should return a missing path param error, got %v", err)
+		}
+	})
+
+	t.Run("TestHTTPErrors", func(t *testing.T) {
+		client := New().EnableHTTPErrors(true).Create()
+
+		resp, err := client.Get(server.URL + "/fail")
+		var httpErr *core.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("it should return an HTTPError, got %v", err)
+		}
+
+		if httpErr.StatusCode != http.StatusBadRequest || httpErr.Method != http.MethodGet || httpErr.URL != server.URL+"/fail" {
+			t.Errorf("it should describe the failed request, got %+v", httpErr)
+		}
+
+		if resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("it should return the response along with the error")
+		}
+
+		if !core.IsClientError(err) {
+			t.Errorf("it should work with the core predicates")
+		}
+
+		if _, err := client.Get(server.URL); err != nil {
+			t.Errorf("it should not fail 2xx responses, got %v", err)
+		}
+	})
+
+	t.Run("TestErrorType", func(t *testing.T) {
+		client := New().SetErrorType(errorResponse{}).Create()
+
+		_, err := client.Get(server.URL + "/fail")
+		var httpErr *core.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("it should return an HTTPError once an error type is registered, got %v", err)
+		}
+
+		decoded, ok := httpErr.Decoded.(*errorResponse)
+		if !ok || decoded.Message != "bad request" {
+			t.Errorf("it should attach the decoded json error body, got %#v", httpErr.Decoded)
+		}
+
+		_, err = client.Get(server.URL + "/fail.xml")
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("it should return an HTTPError, got %v", err)
+		}
+
+		decoded, ok = httpErr.Decoded.(*errorResponse)
+		if !ok || decoded.Message != "conflict" {
+			t.Errorf("it should decode the error body with the response content type, got %#v", httpErr.Decoded)
+		}
+	})
+
+	t.Run("TestRequestErrorTargetWins", func(t *testing.T) {
+		type otherError struct {
+			Message string `json:"message"`
+		}
+
+		client := New().SetErrorType(&errorResponse{}).Create()
+
+		var target otherError
+		_, err := client.R().SetError(&target).Get(server.URL + "/fail")
+
+		var httpErr *core.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Decoded != &target {
+			t.Errorf("it should attach the request error target")
+		}
+
+		if target.Message != "bad request" {
+			t.Errorf("it should decode into the request error target")
+		}
+	})
+
+	t.Run("TestProblemDetails", func(t *testing.T) {
+		resp, err := client.Get(server.URL + "/problem")
+
+		var httpErr *core.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("it should always return an HTTPError for problem details, got %v", err)
+		}
+
+		if httpErr.Problem == nil || httpErr.Problem.Title != "Out of credit" || httpErr.Problem.Extensions["balance"] != float64(30) {
+			t.Errorf("it should attach the problem details, got %+v", httpErr.Problem)
+		}
+
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("it should return the response along with the error")
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package goat
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+// stream runs the request through the middleware chain without reading the
+// response body, middlewares get a response with an empty Body and the live
+// one is handed back to the caller
+func (c *httpClient) stream(ctx context.Context, method string, url string, headers http.Header, body interface{}, options requestOptions) (*core.StreamResponse, error) {
+	request, err := c.newRequest(ctx, method, url, headers, body, options)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.createStreamClient()
+
+	var live io.ReadCloser
+	response, err := c.chain(func(request *http.Request) (*core.Response, error) {
+		ctx := request.Context()
+
+		response, err := c.sendAuthorized(ctx, client, request)
+		if err != nil {
+			return nil, contextError(ctx, err)
+		}
+
+		if _, err := c.decompress(response); err != nil {
+			return nil, contextError(ctx, err)
+		}
+
+		// a middleware calling next again replaces the previous response
+		if live != nil {
+			live.Close()
+		}
+		live = response.Body
+
+		return &core.Response{
+			Status:     response.Status,
+			StatusCode: response.StatusCode,
+			Headers:    response.Header,
+		}, nil
+	})(request)
+
+	if err != nil {
+		if live != nil {
+			live.Close()
+		}
+		return nil, err
+	}
+
+	// a middleware answering on its own, or rewriting the body, wins over the live body
+	if live == nil || response.Body != nil {
+		if live != nil {
+			live.Close()
+		}
+		live = ioutil.NopCloser(bytes.NewReader(response.Body))
+	}
+
+	return &core.StreamResponse{
+		Status:     response.Status,
+		StatusCode: response.StatusCode,
+		Headers:    response.Headers,
+		Body:       live,
+	}, nil
+}
+
+// streamBody releases the request context once the body is closed and
+// reports reads interrupted by the context as a CanceledError
+type streamBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = contextError(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
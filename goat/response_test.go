@@ -0,0 +1,220 @@
+package goat
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+func TestStreamResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Stream", "true")
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		case "/slow":
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		default:
+			w.Write([]byte("chunk 1, chunk 2"))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("TestLiveBody", func(t *testing.T) {
+		client := New().Create()
+
+		resp, err := client.R().Stream(http.MethodGet, server.URL)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || resp.Headers.Get("X-Stream") != "true" {
+			t.Errorf("it should expose status and headers before reading, got %d", resp.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil || string(body) != "chunk 1, chunk 2" {
+			t.Errorf("it should read the body from the connection, got %q, %v", body, err)
+		}
+	})
+
+	t.Run("TestNoHTTPErrors", func(t *testing.T) {
+		client := New().EnableHTTPErrors(true).Create()
+
+		resp, err := client.R().Stream(http.MethodGet, server.URL+"/missing")
+		if err != nil {
+			t.Fatalf("it should leave the status check to the caller, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.IsSuccess() || resp.StatusCode != http.StatusNotFound {
+			t.Errorf("it should return the failed status, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("TestTimeoutWhileReading", func(t *testing.T) {
+		client := New().Create()
+
+		resp, err := client.R().SetTimeout(50*time.Millisecond).Stream(http.MethodGet, server.URL+"/slow")
+		if err != nil {
+			t.Fatalf("it should return the response before the timeout, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		_, err = ioutil.ReadAll(resp.Body)
+		if !errors.Is(err, ErrRequestCanceled) {
+			t.Errorf("it should return a canceled error while reading, got %v", err)
+		}
+	})
+
+	t.Run("TestLongLivedStream", func(t *testing.T) {
+		longServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("first "))
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("last"))
+		}))
+		defer longServer.Close()
+
+		// 100ms overall, shorter than the stream
+		client := New().
+			SetConnectionTimeout(50 * time.Millisecond).
+			SetResponseTimeout(50 * time.Millisecond).
+			Create()
+
+		resp, err := client.R().Stream(http.MethodGet, longServer.URL)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil || string(body) != "first last" {
+			t.Errorf("it should not apply the overall client timeout to the body, got %q, %v", body, err)
+		}
+	})
+
+	t.Run("TestMiddlewares", func(t *testing.T) {
+		var seen int
+		client := New().
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				resp, err := next(request)
+				if err == nil {
+					seen = resp.StatusCode
+				}
+				return resp, err
+			}).
+			Create()
+
+		resp, err := client.R().Stream(http.MethodGet, server.URL)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if seen != http.StatusOK {
+			t.Errorf("it should run the middlewares, got %d", seen)
+		}
+
+		if body, _ := ioutil.ReadAll(resp.Body); string(body) != "chunk 1, chunk 2" {
+			t.Errorf("it should keep the live body, got %q", body)
+		}
+	})
+
+	t.Run("TestShortCircuitMiddleware", func(t *testing.T) {
+		client := New().
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				return &core.Response{StatusCode: http.StatusOK, Body: []byte("cached")}, nil
+			}).
+			Create()
+
+		resp, err := client.R().Stream(http.MethodGet, server.URL)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if body, _ := ioutil.ReadAll(resp.Body); string(body) != "cached" {
+			t.Errorf("it should stream the middleware response, got %q", body)
+		}
+	})
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Size", "large")
+		if r.URL.Path == "/chunked" {
+			// flushing before writing everything hides the length
+			w.Write([]byte("0123"))
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte("456789"))
+	}))
+	defer server.Close()
+
+	t.Run("TestUnderTheLimit", func(t *testing.T) {
+		client := New().SetMaxResponseBytes(10).Create()
+
+		resp, err := client.Get(server.URL, nil)
+		if err != nil || resp.String() != "456789" || resp.Truncated {
+			t.Errorf("it should read bodies under the limit, got %v", err)
+		}
+	})
+
+	t.Run("TestTooLarge", func(t *testing.T) {
+		client := New().SetMaxResponseBytes(5).Create()
+
+		for _, path := range []string{"/", "/chunked"} {
+			resp, err := client.Get(server.URL+path, nil)
+			if resp != nil || !errors.Is(err, ErrResponseTooLarge) {
+				t.Fatalf("it should return a too large error for %s, got %v", path, err)
+			}
+
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("it should return a *ResponseTooLargeError")
+			}
+
+			if tooLarge.StatusCode != http.StatusOK || tooLarge.Headers.Get("X-Size") != "large" || tooLarge.Limit != 5 {
+				t.Errorf("it should keep the status and headers, got %+v", tooLarge)
+			}
+		}
+	})
+
+	t.Run("TestTruncated", func(t *testing.T) {
+		client := New().SetMaxResponseBytes(5).TruncateLargeResponses(true).Create()
+
+		resp, err := client.Get(server.URL+"/chunked", nil)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if !resp.Truncated || resp.String() != "01234" {
+			t.Errorf("it should return the truncated body, got %q", resp.String())
+		}
+	})
+
+	t.Run("TestRequestOverride", func(t *testing.T) {
+		client := New().SetMaxResponseBytes(5).Create()
+
+		if resp, err := client.R().SetMaxResponseBytes(-1).Get(server.URL); err != nil || resp.String() != "456789" {
+			t.Errorf("it should remove the limit for the request, got %v", err)
+		}
+
+		if _, err := New().Create().R().SetMaxResponseBytes(2).Get(server.URL); !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("it should apply the request limit, got %v", err)
+		}
+	})
+}
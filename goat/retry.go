@@ -0,0 +1,228 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+var (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+	defaultRetryMultiplier     = 2.0
+	defaultRetryStatusCodes    = []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+
+	// jitter only needs to spread the retries, it doesn't need to be secure
+	retryRand      = rand.New(rand.NewSource(time.Now().UnixNano()))
+	retryRandMutex sync.Mutex
+)
+
+// RetryPolicy describes how failed requests are retried, zero values fall back
+// to sensible defaults so only the relevant fields need to be set
+type RetryPolicy struct {
+	// MaxAttempts total number of attempts including the first one, 1 or less disables retries
+	MaxAttempts int
+	// InitialBackoff wait before the first retry, defaults to 100ms
+	InitialBackoff time.Duration
+	// MaxBackoff upper bound for any wait between attempts, defaults to 5s
+	MaxBackoff time.Duration
+	// Multiplier growth factor applied to the backoff on every attempt, defaults to 2
+	Multiplier float64
+	// Jitter fraction (0 to 1) of every backoff that is randomized, 0 disables it
+	Jitter float64
+	// RetryStatusCodes response status codes that trigger a retry, defaults to 429, 502, 503 and 504
+	RetryStatusCodes []int
+	// RetryOnError decides which transport errors are retried, by default every
+	// error is retried unless the request context is done
+	RetryOnError func(err error) bool
+	// RetryNonIdempotent allows retrying POST and PATCH requests
+	RetryNonIdempotent bool
+}
+
+// send performs the request, retrying it when a retry policy is configured
+func (c *httpClient) send(ctx context.Context, client core.HttpClient, request *http.Request) (*http.Response, error) {
+	policy := c.config.retryPolicy
+	// streams without a rewind can only be sent once
+	if policy == nil || policy.MaxAttempts <= 1 || !policy.allowsMethod(request.Method) || !replayable(request) {
+		return c.roundTrip(client, request)
+	}
+
+	for attempt := 1; ; attempt++ {
+		// the first attempt sends the request as it is, middlewares may have replaced its body
+		attemptRequest := request
+		if attempt > 1 {
+			var err error
+			if attemptRequest, err = cloneRequest(ctx, request); err != nil {
+				return nil, err
+			}
+		}
+
+		response, err := c.roundTrip(client, attemptRequest)
+		if attempt >= policy.MaxAttempts || !policy.shouldRetry(ctx, response, err) {
+			return response, err
+		}
+
+		wait := policy.backoff(attempt)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+				wait = policy.capBackoff(retryAfter)
+			}
+			// drain the body so the connection can be reused
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		if err := sleepContext(ctx, wait, nil); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// roundTrip performs a single attempt of the request
+func (c *httpClient) roundTrip(client core.HttpClient, request *http.Request) (*http.Response, error) {
+	if err := c.waitRateLimit(request); err != nil {
+		return nil, err
+	}
+
+	// dynamic headers, ex: nonces, are computed right before sending
+	request, err := c.provideHeaders(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.withCircuitBreaker(request, func() (*http.Response, error) {
+		return client.Do(request)
+	})
+}
+
+// cloneRequest returns a copy of the request with a fresh body so it can be sent again
+func cloneRequest(ctx context.Context, request *http.Request) (*http.Request, error) {
+	clone := request.Clone(ctx)
+	if request.GetBody == nil {
+		return clone, nil
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+func (p *RetryPolicy) allowsMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return p.RetryNonIdempotent
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(ctx context.Context, response *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		// hitting an open circuit again won't help until it cools down, and a non
+		// blocking rate limit asked to fail fast instead of waiting
+		if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrRateLimited) {
+			return false
+		}
+		// the header providers failed before sending anything
+		var providerErr *providerError
+		if errors.As(err, &providerErr) {
+			return false
+		}
+		if p.RetryOnError != nil {
+			return p.RetryOnError(err)
+		}
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	codes := p.RetryStatusCodes
+	if codes == nil {
+		codes = defaultRetryStatusCodes
+	}
+	for _, code := range codes {
+		if response.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry, growing exponentially and
+// randomized by the configured jitter
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+
+	wait := p.capBackoff(time.Duration(float64(initial) * math.Pow(multiplier, float64(attempt-1))))
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		retryRandMutex.Lock()
+		random := retryRand.Float64()
+		retryRandMutex.Unlock()
+		wait -= time.Duration(jitter * random * float64(wait))
+	}
+
+	return wait
+}
+
+func (p *RetryPolicy) capBackoff(wait time.Duration) time.Duration {
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = defaultRetryMaxBackoff
+	}
+
+	// overflowed durations turn negative
+	if wait > max || wait < 0 {
+		return max
+	}
+	return wait
+}
+
+// parseRetryAfter supports both formats of the header, delay seconds and http date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
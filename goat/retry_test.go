@@ -0,0 +1,247 @@
+package goat
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andresmijares/goat-rest/core"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("TestRetriesUntilSuccess", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}).
+			Create()
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("it should return status code 200")
+		}
+
+		if attempts != 3 {
+			t.Errorf("it should perform 3 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("TestReturnsLastResponseWhenExhausted", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Create()
+
+		resp, err := client.Delete(server.URL)
+		if err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("it should return the last response")
+		}
+
+		if attempts != 2 {
+			t.Errorf("it should perform 2 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("TestNonIdempotentNotRetriedByDefault", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}).
+			Create()
+
+		client.Post(server.URL, map[string]string{"foo": "bar"})
+		if attempts != 1 {
+			t.Errorf("it should not retry a POST request, got %d attempts", attempts)
+		}
+	})
+
+	t.Run("TestNonIdempotentReplaysBody", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != `{"foo":"bar"}` {
+				t.Errorf("it should replay the request body, got %s", body)
+			}
+
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryNonIdempotent: true}).
+			Create()
+
+		resp, err := client.Post(server.URL, map[string]string{"foo": "bar"})
+		if err != nil {
+			t.Errorf("it should return nil error")
+		}
+
+		if resp.StatusCode != http.StatusCreated {
+			t.Errorf("it should return status code 201")
+		}
+
+		if attempts != 2 {
+			t.Errorf("it should perform 2 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("TestRetriesTransportErrors", func(t *testing.T) {
+		var retried int32
+		client := New().
+			SetRetryPolicy(RetryPolicy{
+				MaxAttempts:    2,
+				InitialBackoff: time.Millisecond,
+				RetryOnError: func(err error) bool {
+					atomic.AddInt32(&retried, 1)
+					return true
+				},
+			}).
+			Create()
+
+		_, err := client.Get("http://localhost")
+		if err == nil {
+			t.Errorf("it should return connection refused")
+		}
+
+		if retried != 1 {
+			t.Errorf("it should consult RetryOnError once, got %d", retried)
+		}
+	})
+
+	t.Run("TestFirstAttemptSendsMiddlewareBody", func(t *testing.T) {
+		bodies := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies <- string(body)
+		}))
+		defer server.Close()
+
+		// the middleware doesn't update GetBody, only a retry would need it
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				request.Body = ioutil.NopCloser(strings.NewReader("rewritten"))
+				request.ContentLength = int64(len("rewritten"))
+				return next(request)
+			}).
+			Create()
+
+		if _, err := client.Put(server.URL, map[string]string{"name": "original"}); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if body := <-bodies; body != "rewritten" {
+			t.Errorf("it should send the middleware body, got %q", body)
+		}
+	})
+
+	t.Run("TestMiddlewareRewritesBody", func(t *testing.T) {
+		bodies := make(chan string, 2)
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies <- string(body)
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Use(func(request *http.Request, next Handler) (*core.Response, error) {
+				rewritten := "rewritten"
+				request.Body = ioutil.NopCloser(strings.NewReader(rewritten))
+				request.ContentLength = int64(len(rewritten))
+				request.GetBody = func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(strings.NewReader(rewritten)), nil
+				}
+				return next(request)
+			}).
+			Create()
+
+		resp, err := client.Put(server.URL, map[string]string{"name": "original"})
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("it should succeed on the second attempt, got %v", err)
+		}
+
+		if first, second := <-bodies, <-bodies; first != "rewritten" || second != "rewritten" {
+			t.Errorf("it should send the middleware body on every attempt, got %q and %q", first, second)
+		}
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	t.Run("TestExponentialBackoff", func(t *testing.T) {
+		policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+		expected := []time.Duration{
+			10 * time.Millisecond,
+			20 * time.Millisecond,
+			40 * time.Millisecond,
+			50 * time.Millisecond,
+		}
+		for i, wait := range expected {
+			if got := policy.backoff(i + 1); got != wait {
+				t.Errorf("attempt %d should wait %v, got %v", i+1, wait, got)
+			}
+		}
+	})
+
+	t.Run("TestJitterStaysInRange", func(t *testing.T) {
+		policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.5}
+		for i := 0; i < 50; i++ {
+			wait := policy.backoff(1)
+			if wait < 50*time.Millisecond || wait > 100*time.Millisecond {
+				t.Errorf("jittered backoff out of range: %v", wait)
+			}
+		}
+	})
+
+	t.Run("TestParseRetryAfter", func(t *testing.T) {
+		wait, ok := parseRetryAfter("2")
+		if !ok || wait != 2*time.Second {
+			t.Errorf("it should parse delay seconds")
+		}
+
+		if _, ok := parseRetryAfter("soon"); ok {
+			t.Errorf("it should ignore invalid values")
+		}
+	})
+}
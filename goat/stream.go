@@ -0,0 +1,130 @@
+package goat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/andresmijares/goat-rest/mime"
+)
+
+// Stream request body read from an io.Reader without buffering it, ex:
+//
+//	file, _ := os.Open("backup.tar")
+//	client.Put(url, &goat.Stream{Reader: file, Length: size})
+//
+// Plain io.Reader bodies are streamed as well, Stream only adds the
+// length and the rewind used by retries
+type Stream struct {
+	Reader io.Reader
+	// Length in bytes, 0 or less when unknown, the body is then sent chunked
+	Length int64
+	// Rewind returns the body from the start again so the request can be
+	// retried, the body isn't replayable without it
+	Rewind func() (io.Reader, error)
+}
+
+func (s *Stream) requestBody() (*requestBody, error) {
+	if s.Reader == nil {
+		return nil, errors.New("stream body without reader")
+	}
+
+	body := &requestBody{
+		reader:             s.Reader,
+		length:             s.Length,
+		defaultContentType: mime.ApplicationTypeOctetStream,
+	}
+	if s.Length <= 0 {
+		body.length = -1
+	}
+
+	if s.Rewind != nil {
+		body.getBody = func() (io.ReadCloser, error) {
+			reader, err := s.Rewind()
+			if err != nil {
+				return nil, err
+			}
+			return toReadCloser(reader), nil
+		}
+	}
+	return body, nil
+}
+
+// JSONStream request body encoded as JSON straight into the connection through
+// a pipe, useful for very large values, the length is unknown so it's sent chunked
+type JSONStream struct {
+	value interface{}
+}
+
+// NewJSONStream creates a body that streams the JSON encoding of value
+func NewJSONStream(value interface{}) *JSONStream {
+	return &JSONStream{value: value}
+}
+
+func (s *JSONStream) requestBody() (*requestBody, error) {
+	return &requestBody{
+		reader: s.reader(),
+		length: -1,
+		// the value is encoded again on every replay
+		getBody: func() (io.ReadCloser, error) {
+			return s.reader(), nil
+		},
+		contentType: mime.ApplicationTypeJSON,
+	}, nil
+}
+
+func (s *JSONStream) reader() io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+	return &lazyPipeReader{
+		PipeReader: pipeReader,
+		start: func() {
+			go func() {
+				pipeWriter.CloseWithError(json.NewEncoder(pipeWriter).Encode(s.value))
+			}()
+		},
+	}
+}
+
+// readerBody streams plain readers, the in memory ones get a known length
+// and can be replayed, the same way http.NewRequest handles them
+func readerBody(reader io.Reader) *requestBody {
+	body := &requestBody{
+		reader:             reader,
+		length:             -1,
+		defaultContentType: mime.ApplicationTypeOctetStream,
+	}
+
+	switch r := reader.(type) {
+	case *bytes.Buffer:
+		data := r.Bytes()
+		body.length = int64(len(data))
+		body.getBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+	case *bytes.Reader:
+		snapshot := *r
+		body.length = int64(r.Len())
+		body.getBody = func() (io.ReadCloser, error) {
+			copied := snapshot
+			return ioutil.NopCloser(&copied), nil
+		}
+	case *strings.Reader:
+		snapshot := *r
+		body.length = int64(r.Len())
+		body.getBody = func() (io.ReadCloser, error) {
+			copied := snapshot
+			return ioutil.NopCloser(&copied), nil
+		}
+	}
+	return body
+}
+
+func toReadCloser(reader io.Reader) io.ReadCloser {
+	if closer, ok := reader.(io.ReadCloser); ok {
+		return closer
+	}
+	return ioutil.NopCloser(reader)
+}
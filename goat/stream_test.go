@@ -0,0 +1,162 @@
+package goat
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type streamResult struct {
+	body          string
+	contentLength int64
+	contentType   string
+}
+
+func newStreamServer(results chan<- streamResult, failures int32) *httptest.Server {
+	var attempts int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		results <- streamResult{
+			body:          string(body),
+			contentLength: r.ContentLength,
+			contentType:   r.Header.Get("Content-Type"),
+		}
+
+		if atomic.AddInt32(&attempts, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+// onlyReader hides any other interface of the wrapped reader
+type onlyReader struct {
+	io.Reader
+}
+
+func TestStreamBodies(t *testing.T) {
+	t.Run("TestPlainReader", func(t *testing.T) {
+		results := make(chan streamResult, 1)
+		server := newStreamServer(results, 0)
+		defer server.Close()
+
+		client := New().Create()
+		if _, err := client.Post(server.URL, onlyReader{strings.NewReader("raw content")}); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		result := <-results
+		if result.body != "raw content" {
+			t.Errorf("it should send the reader as it is, got %s", result.body)
+		}
+
+		if result.contentLength != -1 {
+			t.Errorf("it should send unknown lengths chunked, got %d", result.contentLength)
+		}
+
+		if result.contentType != "application/octet-stream" {
+			t.Errorf("it should default to octet-stream, got %s", result.contentType)
+		}
+	})
+
+	t.Run("TestStreamWithLength", func(t *testing.T) {
+		results := make(chan streamResult, 1)
+		server := newStreamServer(results, 0)
+		defer server.Close()
+
+		client := New().Create()
+		body := &Stream{Reader: onlyReader{strings.NewReader("12345")}, Length: 5}
+		if _, err := client.Put(server.URL, body); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if result := <-results; result.contentLength != 5 || result.body != "12345" {
+			t.Errorf("it should send the explicit length, got %+v", result)
+		}
+	})
+
+	t.Run("TestStreamRewindOnRetry", func(t *testing.T) {
+		results := make(chan streamResult, 2)
+		server := newStreamServer(results, 1)
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Create()
+
+		body := &Stream{
+			Reader: onlyReader{strings.NewReader("payload")},
+			Rewind: func() (io.Reader, error) {
+				return onlyReader{strings.NewReader("payload")}, nil
+			},
+		}
+
+		resp, err := client.Put(server.URL, body)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("it should succeed on the second attempt, got %v", err)
+		}
+
+		if first, second := <-results, <-results; first.body != "payload" || second.body != "payload" {
+			t.Errorf("it should rewind the stream, got %q and %q", first.body, second.body)
+		}
+	})
+
+	t.Run("TestStreamWithoutRewindIsSentOnce", func(t *testing.T) {
+		results := make(chan streamResult, 2)
+		server := newStreamServer(results, 1)
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Create()
+
+		resp, err := client.Put(server.URL, onlyReader{strings.NewReader("payload")})
+		if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("it should not retry a body it can't replay, got %v", err)
+		}
+
+		if len(results) != 1 {
+			t.Errorf("it should perform a single attempt, got %d", len(results))
+		}
+	})
+
+	t.Run("TestInMemoryReadersAreReplayable", func(t *testing.T) {
+		results := make(chan streamResult, 2)
+		server := newStreamServer(results, 1)
+		defer server.Close()
+
+		client := New().
+			SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).
+			Create()
+
+		resp, err := client.Put(server.URL, bytes.NewReader([]byte("bytes")))
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("it should succeed on the second attempt, got %v", err)
+		}
+
+		if first, second := <-results, <-results; second.body != "bytes" || first.contentLength != 5 {
+			t.Errorf("it should replay in memory readers, got %+v and %+v", first, second)
+		}
+	})
+
+	t.Run("TestJSONStream", func(t *testing.T) {
+		results := make(chan streamResult, 1)
+		server := newStreamServer(results, 0)
+		defer server.Close()
+
+		client := New().Create()
+		if _, err := client.Post(server.URL, NewJSONStream(map[string]int{"count": 1})); err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		result := <-results
+		if result.body != "{\"count\":1}\n" || result.contentType != "application/json" {
+			t.Errorf("it should stream the json encoding, got %+v", result)
+		}
+	})
+}
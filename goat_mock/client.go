@@ -11,10 +11,20 @@ type httpClientMock struct {
 }
 
 func (c *httpClientMock) Do(request *http.Request) (*http.Response, error) {
-	requestBody, err := request.GetBody()
-	if err != nil {
+	// honor canceled contexts the same way the real transport does
+	if err := request.Context().Err(); err != nil {
 		return nil, err
 	}
+
+	// bodies that can't be replayed are read directly
+	requestBody := request.Body
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		requestBody = body
+	}
 	defer request.Body.Close()
 
 	body, err := ioutil.ReadAll(requestBody)
@@ -30,7 +40,12 @@ func (c *httpClientMock) Do(request *http.Request) (*http.Response, error) {
 			return nil, mock.Error
 		}
 		response.StatusCode = mock.ResponseStatusCode
-		response.Body = ioutil.NopCloser(strings.NewReader(mock.RequestBody))
+		response.Status = fmt.Sprintf("%d %s", mock.ResponseStatusCode, http.StatusText(mock.ResponseStatusCode))
+		response.Header = mock.ResponseHeaders.Clone()
+		if response.Header == nil {
+			response.Header = make(http.Header)
+		}
+		response.Body = ioutil.NopCloser(strings.NewReader(mock.ResponseBody))
 		response.ContentLength = int64(len(mock.ResponseBody))
 		response.Request = request // in case other values from the response are needed
 		return &response, nil
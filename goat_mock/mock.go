@@ -16,6 +16,8 @@ type Mock struct {
 	Error error
 	ResponseBody string
 	ResponseStatusCode int
+	// ResponseHeaders headers of the mocked response, ex: its Content-Type
+	ResponseHeaders http.Header
 }
 
 // GetResponse gets a response object based on the mock configuration
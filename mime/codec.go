@@ -0,0 +1,127 @@
+package mime
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrNoCodec is returned when there's no codec registered for a content type
+var ErrNoCodec = errors.New("no codec registered for content type")
+
+// Codec encodes and decodes the bodies of a content type
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// NewCodec creates a codec from a pair of marshal and unmarshal functions,
+// ex: NewCodec(json.Marshal, json.Unmarshal)
+func NewCodec(marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) Codec {
+	return &funcCodec{marshal: marshal, unmarshal: unmarshal}
+}
+
+type funcCodec struct {
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+}
+
+func (c *funcCodec) Marshal(v interface{}) ([]byte, error) {
+	if c.marshal == nil {
+		return nil, errors.New("codec doesn't support marshaling")
+	}
+	return c.marshal(v)
+}
+
+func (c *funcCodec) Unmarshal(data []byte, v interface{}) error {
+	if c.unmarshal == nil {
+		return errors.New("codec doesn't support unmarshaling")
+	}
+	return c.unmarshal(data, v)
+}
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[string]Codec{
+		ApplicationTypeJSON: jsonCodec{},
+		ApplicationTypeXML:  NewCodec(xml.Marshal, xml.Unmarshal),
+		TextTypeXML:         NewCodec(xml.Marshal, xml.Unmarshal),
+		ApplicationTypeForm: NewCodec(marshalForm, unmarshalForm),
+	}
+
+	// structured syntax suffixes (RFC 6839) fall back to their base type,
+	// ex: application/vnd.api+json uses the application/json codec
+	suffixes = map[string]string{
+		"+json": ApplicationTypeJSON,
+		"+xml":  ApplicationTypeXML,
+	}
+)
+
+// RegisterCodec registers the codec for the content type, replacing any previous one,
+// content type parameters are ignored
+func RegisterCodec(contentType string, codec Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[MediaType(contentType)] = codec
+}
+
+// LookupCodec returns the codec for the content type, parameters like charset are
+// ignored and +json/+xml suffixed types use the JSON/XML codecs unless they have their own
+func LookupCodec(contentType string) (Codec, error) {
+	mediaType := MediaType(contentType)
+
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, nil
+	}
+
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if base, ok := suffixes[mediaType[i:]]; ok {
+			if codec, ok := codecs[base]; ok {
+				return codec, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrNoCodec, mediaType)
+}
+
+// marshalForm encodes url.Values, maps with string keys and `form:"..."` tagged
+// structs, nested values follow the EncodeValues rules, ex: filter[name]=goat
+func marshalForm(v interface{}) ([]byte, error) {
+	values, err := EncodeValues(v, "form")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values.Encode()), nil
+}
+
+// unmarshalForm decodes into *url.Values, *map[string][]string or *map[string]string,
+// the latter keeps the first value of every key
+func unmarshalForm(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+	case *map[string][]string:
+		*target = values
+	case *map[string]string:
+		decoded := make(map[string]string, len(values))
+		for key := range values {
+			decoded[key] = values.Get(key)
+		}
+		*target = decoded
+	default:
+		return fmt.Errorf("unable to decode form values into %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package mime
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLookupCodec(t *testing.T) {
+	t.Run("TestBuiltInCodecs", func(t *testing.T) {
+		for _, contentType := range []string{
+			"application/json",
+			"application/json; charset=utf-8",
+			"application/vnd.api+json",
+			"application/problem+json",
+			"application/xml",
+			"text/xml; charset=utf-8",
+			"application/atom+xml",
+		} {
+			if _, err := LookupCodec(contentType); err != nil {
+				t.Errorf("it should find a codec for %s, got %v", contentType, err)
+			}
+		}
+	})
+
+	t.Run("TestSuffixUsesBaseCodec", func(t *testing.T) {
+		codec, _ := LookupCodec("application/vnd.api+xml")
+		encoded, err := codec.Marshal([]string{"a"})
+		if err != nil || string(encoded) != "<string>a</string>" {
+			t.Errorf("it should use the xml codec, got %s", encoded)
+		}
+	})
+
+	t.Run("TestUnknownContentType", func(t *testing.T) {
+		if _, err := LookupCodec("text/csv"); !errors.Is(err, ErrNoCodec) {
+			t.Errorf("it should return ErrNoCodec, got %v", err)
+		}
+	})
+
+	t.Run("TestRegisterCodec", func(t *testing.T) {
+		upper := NewCodec(
+			func(v interface{}) ([]byte, error) {
+				return []byte(strings.ToUpper(v.(string))), nil
+			},
+			func(data []byte, v interface{}) error {
+				*(v.(*string)) = strings.ToLower(string(data))
+				return nil
+			},
+		)
+		RegisterCodec("text/x-upper; charset=utf-8", upper)
+
+		codec, err := LookupCodec("TEXT/X-UPPER")
+		if err != nil {
+			t.Fatalf("it should find the registered codec, got %v", err)
+		}
+
+		encoded, _ := codec.Marshal("goat")
+		var decoded string
+		codec.Unmarshal(encoded, &decoded)
+		if string(encoded) != "GOAT" || decoded != "goat" {
+			t.Errorf("it should use the registered codec")
+		}
+	})
+
+	t.Run("TestPartialCodec", func(t *testing.T) {
+		codec := NewCodec(nil, nil)
+		if _, err := codec.Marshal("a"); err == nil {
+			t.Errorf("it should fail without a marshal function")
+		}
+
+		if err := codec.Unmarshal(nil, nil); err == nil {
+			t.Errorf("it should fail without an unmarshal function")
+		}
+	})
+}
+
+func TestFormCodec(t *testing.T) {
+	codec, err := LookupCodec(ApplicationTypeForm + "; charset=utf-8")
+	if err != nil {
+		t.Fatalf("it should find the form codec, got %v", err)
+	}
+
+	t.Run("TestMarshalStruct", func(t *testing.T) {
+		type tokenRequest struct {
+			GrantType string   `form:"grant_type"`
+			Scopes    []string `form:"scope"`
+			Audience  string   `form:"audience,omitempty"`
+			Client    struct {
+				ID string `form:"id"`
+			} `form:"client"`
+		}
+
+		request := tokenRequest{GrantType: "client_credentials", Scopes: []string{"read", "write"}}
+		request.Client.ID = "goat"
+
+		encoded, err := codec.Marshal(request)
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if string(encoded) != "client%5Bid%5D=goat&grant_type=client_credentials&scope=read&scope=write" {
+			t.Errorf("it should encode the form, got %s", encoded)
+		}
+	})
+
+	t.Run("TestMarshalMaps", func(t *testing.T) {
+		encoded, _ := codec.Marshal(map[string]string{"b": "2", "a": "1 2"})
+		if string(encoded) != "a=1+2&b=2" {
+			t.Errorf("it should encode maps sorted by key, got %s", encoded)
+		}
+
+		encoded, _ = codec.Marshal(url.Values{"a": {"1", "2"}})
+		if string(encoded) != "a=1&a=2" {
+			t.Errorf("it should repeat keys, got %s", encoded)
+		}
+	})
+
+	t.Run("TestUnmarshal", func(t *testing.T) {
+		var values url.Values
+		if err := codec.Unmarshal([]byte("a=1&a=2"), &values); err != nil || len(values["a"]) != 2 {
+			t.Errorf("it should decode into url.Values")
+		}
+
+		var flat map[string]string
+		if err := codec.Unmarshal([]byte("a=1&a=2&b=3"), &flat); err != nil || flat["a"] != "1" || flat["b"] != "3" {
+			t.Errorf("it should decode into map[string]string")
+		}
+
+		var unsupported struct{}
+		if err := codec.Unmarshal([]byte("a=1"), &unsupported); err == nil {
+			t.Errorf("it should not decode into a struct")
+		}
+	})
+}
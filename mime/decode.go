@@ -0,0 +1,94 @@
+package mime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrContentTypeMismatch is returned when the body clearly isn't in the format of
+// its content type, ex: an HTML error page sent as application/json
+var ErrContentTypeMismatch = errors.New("body doesn't match its content type")
+
+// DecodeOptions strict mode options, they're honored by the codecs implementing
+// OptionsDecoder, like the built in JSON one
+type DecodeOptions struct {
+	// DisallowUnknownFields fails when the body has fields the target doesn't have
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into interface{} values as json.Number instead of float64
+	UseNumber bool
+}
+
+// OptionsDecoder implemented by the codecs supporting DecodeOptions
+type OptionsDecoder interface {
+	UnmarshalWithOptions(data []byte, v interface{}, options DecodeOptions) error
+}
+
+// Unmarshal decodes data into v with the codec of the content type, the options
+// are ignored by codecs that don't support them, an ErrContentTypeMismatch is
+// returned when a JSON body looks like markup or an XML one looks like JSON
+func Unmarshal(contentType string, data []byte, v interface{}, options DecodeOptions) error {
+	codec, err := LookupCodec(contentType)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBody(contentType, data); err != nil {
+		return err
+	}
+
+	if decoder, ok := codec.(OptionsDecoder); ok {
+		return decoder.UnmarshalWithOptions(data, v, options)
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// checkBody compares the first character of the body with the format of the content type
+func checkBody(contentType string, data []byte) error {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	mediaType := MediaType(contentType)
+	switch first := trimmed[0]; {
+	case IsJSON(mediaType) && first == '<':
+		return fmt.Errorf("%w: %s body looks like XML or HTML", ErrContentTypeMismatch, mediaType)
+	case IsXML(mediaType) && (first == '{' || first == '['):
+		return fmt.Errorf("%w: %s body looks like JSON", ErrContentTypeMismatch, mediaType)
+	}
+	return nil
+}
+
+// jsonCodec built in JSON codec, it supports every DecodeOptions
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) UnmarshalWithOptions(data []byte, v interface{}, options DecodeOptions) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if options.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if options.UseNumber {
+		decoder.UseNumber()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	// the same as json.Unmarshal, nothing but spaces can follow the value
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package mime
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	t.Run("TestDisallowUnknownFields", func(t *testing.T) {
+		data := []byte(`{"name":"goat","age":3}`)
+
+		var target item
+		if err := Unmarshal(ApplicationTypeJSON, data, &target, DecodeOptions{}); err != nil {
+			t.Errorf("it should ignore unknown fields by default, got %v", err)
+		}
+
+		if err := Unmarshal(ApplicationTypeJSON, data, &target, DecodeOptions{DisallowUnknownFields: true}); err == nil {
+			t.Errorf("it should fail on unknown fields")
+		}
+	})
+
+	t.Run("TestUseNumber", func(t *testing.T) {
+		var target map[string]interface{}
+		err := Unmarshal(ApplicationTypeJSON, []byte(`{"id":9007199254740993}`), &target, DecodeOptions{UseNumber: true})
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if number, ok := target["id"].(json.Number); !ok || number.String() != "9007199254740993" {
+			t.Errorf("it should keep the number as json.Number, got %#v", target["id"])
+		}
+	})
+
+	t.Run("TestTrailingData", func(t *testing.T) {
+		var target item
+		if err := Unmarshal(ApplicationTypeJSON, []byte(`{"name":"goat"} {}`), &target, DecodeOptions{UseNumber: true}); err == nil {
+			t.Errorf("it should fail on data after the value")
+		}
+	})
+
+	t.Run("TestContentTypeMismatch", func(t *testing.T) {
+		var target item
+		err := Unmarshal("application/vnd.api+json", []byte("\n<html>bad gateway</html>"), &target, DecodeOptions{})
+		if !errors.Is(err, ErrContentTypeMismatch) {
+			t.Errorf("it should report markup sent as json, got %v", err)
+		}
+
+		err = Unmarshal(TextTypeXML, []byte(`{"name":"goat"}`), &target, DecodeOptions{})
+		if !errors.Is(err, ErrContentTypeMismatch) {
+			t.Errorf("it should report json sent as xml, got %v", err)
+		}
+	})
+
+	t.Run("TestXML", func(t *testing.T) {
+		var target item
+		if err := Unmarshal(ApplicationTypeXML, []byte(`<item><name>goat</name></item>`), &target, DecodeOptions{}); err != nil || target.Name != "goat" {
+			t.Errorf("it should decode xml ignoring unsupported options, got %v", err)
+		}
+	})
+}
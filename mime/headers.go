@@ -1,9 +1,40 @@
 package mime
 
+import "strings"
+
 const (
 	HeaderContentType = "Content-Type"
 	HeaderUserAgent = "User-Agent"
+	HeaderAccept = "Accept"
+	HeaderAcceptEncoding = "Accept-Encoding"
+	HeaderContentEncoding = "Content-Encoding"
+	HeaderContentLength = "Content-Length"
 	
 	ApplicationTypeJSON = "application/json"
 	ApplicationTypeXML = "application/xml"
-)
\ No newline at end of file
+	ApplicationTypeProblemJSON = "application/problem+json"
+	TextTypeXML = "text/xml"
+	ApplicationTypeForm = "application/x-www-form-urlencoded"
+	ApplicationTypeOctetStream = "application/octet-stream"
+)
+
+// MediaType returns the lowercased media type of a content type header,
+// without parameters, ex: "Application/JSON; charset=utf-8" -> "application/json"
+func MediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// IsJSON returns true for JSON content types, including the +json suffixed ones
+func IsJSON(contentType string) bool {
+	mediaType := MediaType(contentType)
+	return mediaType == ApplicationTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// IsXML returns true for XML content types, including the +xml suffixed ones
+func IsXML(contentType string) bool {
+	mediaType := MediaType(contentType)
+	return mediaType == ApplicationTypeXML || mediaType == TextTypeXML || strings.HasSuffix(mediaType, "+xml")
+}
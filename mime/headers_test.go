@@ -11,6 +11,10 @@ func TestHeaders(t *testing.T) {
 		t.Error("Invalid content type")
 	}
 
+	if HeaderAccept != "Accept" {
+		t.Error("Invalid content type")
+	}
+
 	if ApplicationTypeJSON != "application/json" {
 		t.Error("Invalid content type")
 	}
@@ -18,4 +22,40 @@ func TestHeaders(t *testing.T) {
 	if ApplicationTypeXML != "application/xml" {
 		t.Error("Invalid content type")
 	}
-}
\ No newline at end of file
+
+	if ApplicationTypeProblemJSON != "application/problem+json" {
+		t.Error("Invalid content type")
+	}
+
+	if TextTypeXML != "text/xml" {
+		t.Error("Invalid content type")
+	}
+
+	if ApplicationTypeForm != "application/x-www-form-urlencoded" {
+		t.Error("Invalid content type")
+	}
+
+	if ApplicationTypeOctetStream != "application/octet-stream" {
+		t.Error("Invalid content type")
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	if MediaType("Application/JSON; charset=utf-8") != ApplicationTypeJSON {
+		t.Error("Invalid media type")
+	}
+
+	if MediaType(" application/xml ") != ApplicationTypeXML {
+		t.Error("Invalid media type")
+	}
+}
+
+func TestIsJSONAndXML(t *testing.T) {
+	if !IsJSON("application/vnd.api+json; charset=utf-8") || !IsJSON(ApplicationTypeProblemJSON) || IsJSON(ApplicationTypeForm) {
+		t.Errorf("it should match the JSON content types")
+	}
+
+	if !IsXML("Text/XML") || !IsXML("application/atom+xml") || IsXML(ApplicationTypeJSON) {
+		t.Errorf("it should match the XML content types")
+	}
+}
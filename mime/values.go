@@ -0,0 +1,292 @@
+package mime
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// EncodeValues encodes v with the application/x-www-form-urlencoded rules used by
+// query strings and form bodies, v can be url.Values, a map with string keys or a
+// struct (or pointer to struct) whose fields are named by the given tag, ex: `url:"name,omitempty"`
+//
+// Encoding rules:
+//   - a "-" tag skips the field, untagged fields use the field name
+//   - omitempty skips zero values, nil pointers are always skipped
+//   - slices and arrays repeat the key for every element, unless the comma option
+//     is set, then elements are joined with commas
+//   - time.Time uses RFC3339 unless a `layout:"..."` tag is present, the unix
+//     option encodes it as unix seconds
+//   - nested structs and maps use brackets, ex: filter[name]=goat, slices of structs
+//     include the index, ex: items[0][id]=1
+//   - embedded structs are flattened into the parent
+//   - types implementing encoding.TextMarshaler use their text representation
+func EncodeValues(v interface{}, tag string) (url.Values, error) {
+	values := make(url.Values)
+	if v == nil {
+		return values, nil
+	}
+
+	if given, ok := v.(url.Values); ok {
+		for key, list := range given {
+			values[key] = append([]string(nil), list...)
+		}
+		return values, nil
+	}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return values, nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if err := encodeStruct(values, "", value, tag); err != nil {
+			return nil, err
+		}
+	case reflect.Map:
+		if err := encodeMap(values, "", value, tag); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unable to encode values from %s", value.Type())
+	}
+
+	return values, nil
+}
+
+type fieldOptions struct {
+	omitEmpty bool
+	comma     bool
+	unix      bool
+	layout    string
+}
+
+func encodeStruct(values url.Values, prefix string, value reflect.Value, tag string) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+
+		name, opts := parseTag(field, tag)
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+
+		// embedded structs without a name are promoted into the parent
+		if field.Anonymous && field.Tag.Get(tag) == "" {
+			for fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					break
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+				if err := encodeStruct(values, prefix, fieldValue, tag); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err := encodeValue(values, joinKey(prefix, name), fieldValue, opts, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeMap(values url.Values, prefix string, value reflect.Value, tag string) error {
+	if value.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("unable to encode values from %s, keys must be strings", value.Type())
+	}
+
+	iter := value.MapRange()
+	for iter.Next() {
+		key := joinKey(prefix, iter.Key().String())
+		if err := encodeValue(values, key, iter.Value(), fieldOptions{}, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeValue(values url.Values, key string, value reflect.Value, opts fieldOptions, tag string) error {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if opts.omitEmpty && value.IsZero() {
+		return nil
+	}
+
+	if value.Type() == timeType {
+		values.Add(key, formatTime(value.Interface().(time.Time), opts))
+		return nil
+	}
+
+	if value.Type().Implements(textMarshalerType) {
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		values.Add(key, string(text))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		// []byte is a string, not a list of numbers
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(key, string(value.Bytes()))
+			return nil
+		}
+
+		if opts.comma {
+			items := make([]string, 0, value.Len())
+			for i := 0; i < value.Len(); i++ {
+				item, err := formatScalar(value.Index(i), opts)
+				if err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+			values.Add(key, strings.Join(items, ","))
+			return nil
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if isNested(item) {
+				if err := encodeValue(values, joinKey(key, strconv.Itoa(i)), item, fieldOptions{}, tag); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := encodeValue(values, key, item, fieldOptions{unix: opts.unix, layout: opts.layout}, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		return encodeStruct(values, key, value, tag)
+	case reflect.Map:
+		return encodeMap(values, key, value, tag)
+	}
+
+	formatted, err := formatScalar(value, opts)
+	if err != nil {
+		return err
+	}
+	values.Add(key, formatted)
+	return nil
+}
+
+func formatScalar(value reflect.Value, opts fieldOptions) (string, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "", nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Type() == timeType {
+		return formatTime(value.Interface().(time.Time), opts), nil
+	}
+
+	if value.Type().Implements(textMarshalerType) {
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unable to encode value of type %s", value.Type())
+	}
+}
+
+func formatTime(t time.Time, opts fieldOptions) string {
+	if opts.unix {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	if opts.layout != "" {
+		return t.Format(opts.layout)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// isNested reports whether the value needs bracket keys to be encoded
+func isNested(value reflect.Value) bool {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return false
+		}
+		value = value.Elem()
+	}
+
+	if value.Type() == timeType || value.Type().Implements(textMarshalerType) {
+		return false
+	}
+	return value.Kind() == reflect.Struct || value.Kind() == reflect.Map
+}
+
+func parseTag(field reflect.StructField, tag string) (string, fieldOptions) {
+	opts := fieldOptions{layout: field.Tag.Get("layout")}
+
+	parts := strings.Split(field.Tag.Get(tag), ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, option := range parts[1:] {
+		switch option {
+		case "omitempty":
+			opts.omitEmpty = true
+		case "comma":
+			opts.comma = true
+		case "unix":
+			opts.unix = true
+		}
+	}
+
+	return name, opts
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "[" + key + "]"
+}
@@ -0,0 +1,128 @@
+package mime
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+type paging struct {
+	Page  int `url:"page,omitempty"`
+	Limit int `url:"limit"`
+}
+
+type searchParams struct {
+	paging
+	Paging
+	Query    string            `url:"q"`
+	Tags     []string          `url:"tag"`
+	IDs      []int             `url:"ids,comma"`
+	Since    time.Time         `url:"since"`
+	Day      time.Time         `url:"day" layout:"2006-01-02"`
+	Until    time.Time         `url:"until,unix"`
+	Missing  *string           `url:"missing"`
+	Ignored  string            `url:"-"`
+	Filter   searchFilter      `url:"filter"`
+	Items    []searchFilter    `url:"items"`
+	Extra    map[string]string `url:"extra"`
+	Untagged bool
+	internal string
+}
+
+type Paging struct {
+	Offset int `url:"offset"`
+}
+
+type searchFilter struct {
+	Name string `url:"name"`
+}
+
+func TestEncodeValues(t *testing.T) {
+	t.Run("TestStruct", func(t *testing.T) {
+		date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		params := searchParams{
+			paging:   paging{Page: 1, Limit: 10},
+			Paging:   Paging{Offset: 20},
+			Query:    "goat rest",
+			Tags:     []string{"a", "b"},
+			IDs:      []int{1, 2, 3},
+			Since:    date,
+			Day:      date,
+			Until:    date,
+			Ignored:  "ignored",
+			Filter:   searchFilter{Name: "goat"},
+			Items:    []searchFilter{{Name: "first"}, {Name: "second"}},
+			Extra:    map[string]string{"key": "value"},
+			Untagged: true,
+			internal: "internal",
+		}
+
+		values, err := EncodeValues(&params, "url")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		expected := url.Values{
+			"offset":         {"20"},
+			"q":              {"goat rest"},
+			"tag":            {"a", "b"},
+			"ids":            {"1,2,3"},
+			"since":          {"2020-01-02T03:04:05Z"},
+			"day":            {"2020-01-02"},
+			"until":          {"1577934245"},
+			"filter[name]":   {"goat"},
+			"items[0][name]": {"first"},
+			"items[1][name]": {"second"},
+			"extra[key]":     {"value"},
+			"Untagged":       {"true"},
+		}
+
+		if values.Encode() != expected.Encode() {
+			t.Errorf("it should encode the struct\n got: %s\nwant: %s", values.Encode(), expected.Encode())
+		}
+	})
+
+	t.Run("TestOmitEmpty", func(t *testing.T) {
+		values, err := EncodeValues(paging{}, "url")
+		if err != nil {
+			t.Fatalf("it should return nil error, got %v", err)
+		}
+
+		if values.Encode() != "limit=0" {
+			t.Errorf("it should omit empty values, got %s", values.Encode())
+		}
+	})
+
+	t.Run("TestMaps", func(t *testing.T) {
+		values, err := EncodeValues(map[string]string{"a": "1"}, "url")
+		if err != nil || values.Get("a") != "1" {
+			t.Errorf("it should encode map[string]string")
+		}
+
+		values, err = EncodeValues(url.Values{"a": {"1", "2"}}, "url")
+		if err != nil || len(values["a"]) != 2 {
+			t.Errorf("it should keep repeated url.Values keys")
+		}
+	})
+
+	t.Run("TestCustomTag", func(t *testing.T) {
+		type login struct {
+			User string `form:"username"`
+		}
+
+		values, err := EncodeValues(login{User: "goat"}, "form")
+		if err != nil || values.Get("username") != "goat" {
+			t.Errorf("it should use the given tag name")
+		}
+	})
+
+	t.Run("TestUnsupported", func(t *testing.T) {
+		if _, err := EncodeValues("plain", "url"); err == nil {
+			t.Errorf("it should not encode a string")
+		}
+
+		if _, err := EncodeValues(map[string]interface{}{"ch": make(chan int)}, "url"); err == nil {
+			t.Errorf("it should not encode a channel")
+		}
+	})
+}